@@ -0,0 +1,66 @@
+package octopusenergyapi
+
+import "context"
+
+// API is the set of operations provided by Client. Code depending on API
+// rather than *Client can be tested with a fake, such as octopustest.Fake.
+type API interface {
+	GetMeterPoint(mpan string) (MeterPoint, error)
+	GetMeterPointContext(ctx context.Context, mpan string) (MeterPoint, error)
+	GetGasMeterPoint(mprn string) (GasMeterPoint, error)
+	GetGasMeterPointContext(ctx context.Context, mprn string) (GasMeterPoint, error)
+
+	GetGridSupplyPoint(postcode string) (GridSupplyPoint, error)
+	GetGridSupplyPointContext(ctx context.Context, postcode string) (GridSupplyPoint, error)
+	GridSupplyPointsSeq(ctx context.Context, postcode string) func(yield func(GridSupplyPoint, error) bool)
+
+	GetElecMeterConsumption(mpan, serialNo string, options ConsumptionOption) ([]Consumption, error)
+	GetElecMeterConsumptionContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption) ([]Consumption, error)
+	GetElecMeterConsumptionAll(mpan, serialNo string, options ConsumptionOption) (ConsumptionPage, error)
+	GetElecMeterConsumptionAllContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption) (ConsumptionPage, error)
+	GetElecMeterConsumptionPage(mpan, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error)
+	GetElecMeterConsumptionPageContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error)
+	ElecMeterConsumptionSeq(ctx context.Context, mpan, serialNo string, options ConsumptionOption) func(yield func(Consumption, error) bool)
+	GetGasMeterConsumption(mprn, serialNo string, options ConsumptionOption) ([]Consumption, error)
+	GetGasMeterConsumptionContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption) ([]Consumption, error)
+	GetGasMeterConsumptionAll(mprn, serialNo string, options ConsumptionOption) (ConsumptionPage, error)
+	GetGasMeterConsumptionAllContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption) (ConsumptionPage, error)
+	GetGasMeterConsumptionPage(mprn, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error)
+	GetGasMeterConsumptionPageContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error)
+	GasMeterConsumptionSeq(ctx context.Context, mprn, serialNo string, options ConsumptionOption) func(yield func(Consumption, error) bool)
+
+	ListProducts() ([]Product, error)
+	ListProductsContext(ctx context.Context) ([]Product, error)
+	ListProductsWithOptions(options ListProductsOption) ([]Product, error)
+	ListProductsWithOptionsContext(ctx context.Context, options ListProductsOption) ([]Product, error)
+	ProductsSeq(ctx context.Context, options ListProductsOption) func(yield func(Product, error) bool)
+	GetProduct(productCode string) (Product, error)
+	GetProductContext(ctx context.Context, productCode string) (Product, error)
+	GetProductWithOptions(productCode string, options ProductOption) (Product, error)
+	GetProductWithOptionsContext(ctx context.Context, productCode string, options ProductOption) (Product, error)
+
+	GetElecStandardUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetElecStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	ElecStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+	GetGasStandardUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetGasStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GasStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+	GetElecDayUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetElecDayUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	ElecDayUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+	GetElecNightUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetElecNightUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	ElecNightUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+	GetElecStandingCharges(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetElecStandingChargesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	ElecStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+	GetGasStandingCharges(productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GetGasStandingChargesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error)
+	GasStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool)
+
+	GetAccount(accountNumber string) (Account, error)
+	GetAccountContext(ctx context.Context, accountNumber string) (Account, error)
+}
+
+// Client has to implement every operation of API
+var _ API = (*Client)(nil)
@@ -0,0 +1,318 @@
+// Package billing calculates itemised bills from meter consumption and
+// tariff price history.
+//
+// Consumption is expected in kWh. Unit rates are in pence per kWh and
+// standing charges in pence per day, as returned by the API.
+package billing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// ErrNoRate is returned when no rate is valid for a part of the billed period
+var ErrNoRate = errors.New("no rate found")
+
+// Tariff is the price history of a tariff
+type Tariff struct {
+	// UnitRates are unit rates in p/kWh. Time-of-use tariffs, such as
+	// Agile, have a separate rate for each half-hour.
+	UnitRates []octopusenergyapi.Rate
+	// StandingCharges are standing charges in p/day
+	StandingCharges []octopusenergyapi.Rate
+}
+
+// Options represents optional parameters for Calculate
+type Options struct {
+	// Location determines boundaries of days and months, defaults to UTC
+	Location *time.Location
+	// From and To limit the billed period. A full standing charge is billed
+	// for every day in the period, including days without consumption and
+	// days only partly in the period. Defaults to days covered by consumption.
+	From time.Time
+	To   time.Time
+	// PaymentMethod selects rates of a payment method, e.g. DIRECT_DEBIT.
+	// Rates without a payment method always apply. If it is empty, rates
+	// should not be of more than one payment method.
+	PaymentMethod string
+}
+
+// Amount is an amount in pence
+type Amount struct {
+	ExcVAT float64
+	IncVAT float64
+}
+
+// VAT returns VAT included in the amount
+func (a Amount) VAT() float64 {
+	return a.IncVAT - a.ExcVAT
+}
+
+// Add returns sum of amounts
+func (a Amount) Add(b Amount) Amount {
+	return Amount{ExcVAT: a.ExcVAT + b.ExcVAT, IncVAT: a.IncVAT + b.IncVAT}
+}
+
+// Interval is cost of consumption in a single interval
+type Interval struct {
+	Start time.Time
+	End   time.Time
+	// Consumption in kWh
+	Consumption float64
+	// UnitRate is the rate in p/kWh. If rate changes within the interval,
+	// it is the average rate weighted by time.
+	UnitRate Amount
+	Cost     Amount
+}
+
+// Total is the cost of a period, such as a day or a month
+type Total struct {
+	Start time.Time
+	End   time.Time
+	// Consumption in kWh
+	Consumption    float64
+	UnitCost       Amount
+	StandingCharge Amount
+}
+
+// Cost returns the total cost, including standing charge
+func (t Total) Cost() Amount {
+	return t.UnitCost.Add(t.StandingCharge)
+}
+
+func (t *Total) add(other Total) {
+	t.Consumption += other.Consumption
+	t.UnitCost = t.UnitCost.Add(other.UnitCost)
+	t.StandingCharge = t.StandingCharge.Add(other.StandingCharge)
+}
+
+// Bill is an itemised bill
+type Bill struct {
+	// Intervals are costs of consumption intervals, ordered by time
+	Intervals []Interval
+	// Days are daily totals, including standing charges
+	Days []Total
+	// Months are monthly totals
+	Months []Total
+	// Total is the total of the billed period
+	Total Total
+}
+
+// Calculate calculates a bill for consumption, which can be in any order.
+// Consumption intervals are split between rates when the rate changes within
+// an interval, and are billed to the day they start in. Likewise, the standing
+// charge of a day is split between standing charges valid in the billed part
+// of the day.
+func Calculate(consumption []octopusenergyapi.Consumption, tariff Tariff, options Options) (Bill, error) {
+	loc := options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	unitRates, err := newRateIndex(tariff.UnitRates, options.PaymentMethod)
+	if err != nil {
+		return Bill{}, errors.Wrap(err, "unit rates")
+	}
+	standingCharges, err := newRateIndex(tariff.StandingCharges, options.PaymentMethod)
+	if err != nil {
+		return Bill{}, errors.Wrap(err, "standing charges")
+	}
+
+	intervals := make([]octopusenergyapi.Consumption, 0, len(consumption))
+	for _, c := range consumption {
+		if !options.From.IsZero() && c.IntervalStart.Before(options.From) {
+			continue
+		}
+		if !options.To.IsZero() && !c.IntervalStart.Before(options.To) {
+			continue
+		}
+		intervals = append(intervals, c)
+	}
+	sort.SliceStable(intervals, func(i, j int) bool {
+		return intervals[i].IntervalStart.Before(intervals[j].IntervalStart)
+	})
+
+	// Determine billed days
+	from, to := options.From, options.To
+	if from.IsZero() && len(intervals) > 0 {
+		from = intervals[0].IntervalStart
+	}
+	if to.IsZero() && len(intervals) > 0 {
+		to = intervals[len(intervals)-1].IntervalEnd
+	}
+
+	var bill Bill
+	if from.IsZero() || !to.After(from) {
+		return bill, nil
+	}
+
+	days := make(map[time.Time]*Total)
+	for day := startOfDay(from, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+
+		// Only standing charges valid in the billed part of the day apply
+		start, chargeEnd := day, end
+		if start.Before(from) {
+			start = from
+		}
+		if chargeEnd.After(to) {
+			chargeEnd = to
+		}
+
+		charge, err := standingCharges.average(start, chargeEnd)
+		if err != nil {
+			return Bill{}, errors.Wrapf(err, "standing charge on %s", day.Format("2006-01-02"))
+		}
+
+		bill.Days = append(bill.Days, Total{
+			Start:          day,
+			End:            end,
+			StandingCharge: charge,
+		})
+	}
+	for i := range bill.Days {
+		days[bill.Days[i].Start] = &bill.Days[i]
+	}
+
+	bill.Intervals = make([]Interval, 0, len(intervals))
+	for _, c := range intervals {
+		interval, err := unitRates.cost(c)
+		if err != nil {
+			return Bill{}, err
+		}
+		bill.Intervals = append(bill.Intervals, interval)
+
+		if day, ok := days[startOfDay(c.IntervalStart, loc)]; ok {
+			day.Consumption += interval.Consumption
+			day.UnitCost = day.UnitCost.Add(interval.Cost)
+		}
+	}
+
+	for _, day := range bill.Days {
+		month := time.Date(day.Start.Year(), day.Start.Month(), 1, 0, 0, 0, 0, loc)
+		if len(bill.Months) == 0 || !bill.Months[len(bill.Months)-1].Start.Equal(month) {
+			bill.Months = append(bill.Months, Total{Start: month, End: month.AddDate(0, 1, 0)})
+		}
+		bill.Months[len(bill.Months)-1].add(day)
+		bill.Total.add(day)
+	}
+
+	bill.Total.Start = bill.Days[0].Start
+	bill.Total.End = bill.Days[len(bill.Days)-1].End
+	// Partial months end with the billed period
+	bill.Months[0].Start = bill.Total.Start
+	bill.Months[len(bill.Months)-1].End = bill.Total.End
+
+	return bill, nil
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// rateIndex finds rates valid at a given time
+type rateIndex []octopusenergyapi.Rate
+
+// newRateIndex returns an index of rates of a payment method. If paymentMethod
+// is empty, rates can't be of more than one payment method, as it would be
+// ambiguous which of them applies.
+func newRateIndex(rates []octopusenergyapi.Rate, paymentMethod string) (rateIndex, error) {
+	index := make(rateIndex, 0, len(rates))
+	var found string
+	for _, r := range rates {
+		if r.PaymentMethod != "" {
+			if paymentMethod != "" && r.PaymentMethod != paymentMethod {
+				continue
+			}
+			if found != "" && r.PaymentMethod != found {
+				return nil, errors.Errorf("rates of payment methods %s and %s, payment method should be set", found, r.PaymentMethod)
+			}
+			found = r.PaymentMethod
+		}
+		index = append(index, r)
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].ValidFrom.Before(index[j].ValidFrom)
+	})
+
+	return index, nil
+}
+
+// next returns position of the first rate starting after t
+func (index rateIndex) next(t time.Time) int {
+	return sort.Search(len(index), func(i int) bool {
+		return index[i].ValidFrom.After(t)
+	})
+}
+
+// at returns the rate valid at t. If more rates are valid, the most recent
+// one is used.
+func (index rateIndex) at(t time.Time) (octopusenergyapi.Rate, bool) {
+	for i := index.next(t) - 1; i >= 0; i-- {
+		if index[i].ValidTo.IsZero() || index[i].ValidTo.After(t) {
+			return index[i], true
+		}
+	}
+
+	return octopusenergyapi.Rate{}, false
+}
+
+// average returns the rate between start and end, split between rates
+// when the rate changes, weighted by time
+func (index rateIndex) average(start, end time.Time) (Amount, error) {
+	duration := end.Sub(start)
+	if duration <= 0 {
+		return Amount{}, errors.Errorf("invalid interval %s - %s", start, end)
+	}
+
+	var average Amount
+	for t := start; t.Before(end); {
+		rate, ok := index.at(t)
+		if !ok {
+			return Amount{}, errors.Wrapf(ErrNoRate, "at %s", t.Format(time.RFC3339))
+		}
+
+		// Rate applies until it expires or is superseded
+		rateEnd := end
+		if !rate.ValidTo.IsZero() && rate.ValidTo.Before(rateEnd) {
+			rateEnd = rate.ValidTo
+		}
+		if next := index.next(t); next < len(index) && index[next].ValidFrom.Before(rateEnd) {
+			rateEnd = index[next].ValidFrom
+		}
+
+		share := float64(rateEnd.Sub(t)) / float64(duration)
+		average = average.Add(Amount{
+			ExcVAT: float64(rate.ValueExcVAT) * share,
+			IncVAT: float64(rate.ValueIncVAT) * share,
+		})
+
+		t = rateEnd
+	}
+
+	return average, nil
+}
+
+// cost calculates cost of a consumption interval, splitting it between rates
+func (index rateIndex) cost(c octopusenergyapi.Consumption) (Interval, error) {
+	rate, err := index.average(c.IntervalStart, c.IntervalEnd)
+	if err != nil {
+		return Interval{}, errors.Wrap(err, "unit rate")
+	}
+
+	return Interval{
+		Start:       c.IntervalStart,
+		End:         c.IntervalEnd,
+		Consumption: float64(c.Value),
+		UnitRate:    rate,
+		Cost: Amount{
+			ExcVAT: rate.ExcVAT * float64(c.Value),
+			IncVAT: rate.IncVAT * float64(c.Value),
+		},
+	}, nil
+}
@@ -0,0 +1,225 @@
+package billing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/octopustest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+// halfHourly returns consumption of value in every half-hour between from and to
+func halfHourly(from, to time.Time, value float32) []octopusenergyapi.Consumption {
+	var consumption []octopusenergyapi.Consumption
+	for t := from; t.Before(to); t = t.Add(30 * time.Minute) {
+		consumption = append(consumption, octopusenergyapi.Consumption{Value: value, IntervalStart: t, IntervalEnd: t.Add(30 * time.Minute)})
+	}
+
+	return consumption
+}
+
+var standingCharge = []octopusenergyapi.Rate{{ValueExcVAT: 20, ValueIncVAT: 21, ValidFrom: date(2020, 1, 1, 0, 0)}}
+
+func TestCalculateFixed(t *testing.T) {
+	tariff := Tariff{
+		UnitRates:       []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: date(2020, 1, 1, 0, 0)}},
+		StandingCharges: standingCharge,
+	}
+
+	// API returns most recent consumption first
+	consumption := halfHourly(date(2020, 1, 31, 0, 0), date(2020, 2, 2, 0, 0), 0.5)
+	for i, j := 0, len(consumption)-1; i < j; i, j = i+1, j-1 {
+		consumption[i], consumption[j] = consumption[j], consumption[i]
+	}
+
+	bill, err := Calculate(consumption, tariff, Options{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if assert.Len(t, bill.Intervals, 96) {
+		assert.Equal(t, date(2020, 1, 31, 0, 0), bill.Intervals[0].Start)
+		assert.InDelta(t, 5, bill.Intervals[0].Cost.ExcVAT, 1e-9)
+		assert.InDelta(t, 10.5, bill.Intervals[0].UnitRate.IncVAT, 1e-9)
+	}
+
+	if assert.Len(t, bill.Days, 2) {
+		day := bill.Days[0]
+		assert.Equal(t, date(2020, 1, 31, 0, 0), day.Start)
+		assert.InDelta(t, 24, day.Consumption, 1e-9)
+		assert.InDelta(t, 240, day.UnitCost.ExcVAT, 1e-9)
+		assert.InDelta(t, 20, day.StandingCharge.ExcVAT, 1e-9)
+		assert.InDelta(t, 273, day.Cost().IncVAT, 1e-9)
+		assert.InDelta(t, 13, day.Cost().VAT(), 1e-9)
+	}
+
+	if assert.Len(t, bill.Months, 2) {
+		assert.Equal(t, date(2020, 1, 31, 0, 0), bill.Months[0].Start)
+		assert.Equal(t, date(2020, 2, 1, 0, 0), bill.Months[0].End)
+		assert.Equal(t, date(2020, 2, 2, 0, 0), bill.Months[1].End)
+	}
+
+	assert.InDelta(t, 48, bill.Total.Consumption, 1e-9)
+	assert.InDelta(t, 520, bill.Total.Cost().ExcVAT, 1e-9)
+}
+
+func TestCalculateRateChange(t *testing.T) {
+	tariff := Tariff{
+		UnitRates: []octopusenergyapi.Rate{
+			{ValueExcVAT: 20, ValueIncVAT: 21, ValidFrom: date(2020, 3, 1, 12, 0)},
+			{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: date(2020, 1, 1, 0, 0), ValidTo: date(2020, 3, 1, 12, 0)},
+		},
+		StandingCharges: standingCharge,
+	}
+
+	// Daily consumption, rate changes at noon
+	consumption := []octopusenergyapi.Consumption{{Value: 10, IntervalStart: date(2020, 3, 1, 0, 0), IntervalEnd: date(2020, 3, 2, 0, 0)}}
+
+	bill, err := Calculate(consumption, tariff, Options{})
+	if assert.Nil(t, err) && assert.Len(t, bill.Intervals, 1) {
+		assert.InDelta(t, 15, bill.Intervals[0].UnitRate.ExcVAT, 1e-9)
+		assert.InDelta(t, 150, bill.Intervals[0].Cost.ExcVAT, 1e-9)
+	}
+}
+
+func TestCalculateStandingChargeChange(t *testing.T) {
+	tariff := Tariff{
+		UnitRates: []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: date(2020, 1, 1, 0, 0)}},
+		StandingCharges: []octopusenergyapi.Rate{
+			{ValueExcVAT: 30, ValueIncVAT: 31.5, ValidFrom: date(2020, 3, 1, 18, 0)},
+			{ValueExcVAT: 20, ValueIncVAT: 21, ValidFrom: date(2020, 1, 1, 0, 0), ValidTo: date(2020, 3, 1, 18, 0)},
+		},
+	}
+
+	// Standing charge changes at 18:00, so a quarter of the day is billed at the new one
+	bill, err := Calculate(halfHourly(date(2020, 3, 1, 0, 0), date(2020, 3, 3, 0, 0), 1), tariff, Options{})
+	if assert.Nil(t, err) && assert.Len(t, bill.Days, 2) {
+		assert.InDelta(t, 22.5, bill.Days[0].StandingCharge.ExcVAT, 1e-9)
+		assert.InDelta(t, 30, bill.Days[1].StandingCharge.ExcVAT, 1e-9)
+	}
+
+	// Period starting after midnight is billed a full standing charge of the
+	// rate valid then, even if no rate is valid at midnight
+	tariff.StandingCharges = tariff.StandingCharges[:1]
+	bill, err = Calculate(halfHourly(date(2020, 3, 1, 18, 0), date(2020, 3, 2, 0, 0), 1), tariff, Options{})
+	if assert.Nil(t, err) && assert.Len(t, bill.Days, 1) {
+		assert.Equal(t, date(2020, 3, 1, 0, 0), bill.Days[0].Start)
+		assert.InDelta(t, 30, bill.Days[0].StandingCharge.ExcVAT, 1e-9)
+	}
+}
+
+func TestCalculateAgile(t *testing.T) {
+	var rates []octopusenergyapi.Rate
+	for i, t := 0, date(2020, 11, 26, 0, 0); i < 48; i, t = i+1, t.Add(30*time.Minute) {
+		rates = append(rates, octopusenergyapi.Rate{
+			ValueExcVAT: float32(i),
+			ValueIncVAT: float32(i) * 1.05,
+			ValidFrom:   t,
+			ValidTo:     t.Add(30 * time.Minute),
+		})
+	}
+
+	tariff := Tariff{UnitRates: rates, StandingCharges: standingCharge}
+	consumption := halfHourly(date(2020, 11, 26, 0, 0), date(2020, 11, 27, 0, 0), 1)
+
+	bill, err := Calculate(consumption, tariff, Options{})
+	if assert.Nil(t, err) && assert.Len(t, bill.Intervals, 48) {
+		for i, interval := range bill.Intervals {
+			assert.InDelta(t, float64(i), interval.Cost.ExcVAT, 1e-9)
+		}
+		// Sum of 0..47
+		assert.InDelta(t, 1128, bill.Total.UnitCost.ExcVAT, 1e-9)
+	}
+
+	// No rate for the following day
+	_, err = Calculate(halfHourly(date(2020, 11, 27, 0, 0), date(2020, 11, 27, 1, 0), 1), tariff, Options{})
+	assert.True(t, errors.Is(err, ErrNoRate))
+}
+
+func TestCalculateOptions(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tariff := Tariff{
+		UnitRates: []octopusenergyapi.Rate{
+			{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: date(2020, 1, 1, 0, 0), PaymentMethod: "DIRECT_DEBIT"},
+			{ValueExcVAT: 11, ValueIncVAT: 11.55, ValidFrom: date(2020, 1, 1, 0, 0), PaymentMethod: "NON_DIRECT_DEBIT"},
+		},
+		StandingCharges: standingCharge,
+	}
+
+	// A day in British Summer Time, from 23:00 UTC
+	consumption := halfHourly(date(2020, 6, 30, 23, 0), date(2020, 7, 1, 23, 0), 1)
+
+	bill, err := Calculate(consumption, tariff, Options{
+		Location:      london,
+		PaymentMethod: "DIRECT_DEBIT",
+		From:          date(2020, 6, 30, 23, 0),
+		To:            date(2020, 7, 3, 23, 0),
+	})
+	if assert.Nil(t, err) && assert.Len(t, bill.Days, 3) {
+		assert.True(t, date(2020, 6, 30, 23, 0).Equal(bill.Days[0].Start))
+		assert.InDelta(t, 480, bill.Days[0].UnitCost.ExcVAT, 1e-9)
+		// Standing charge applies to days without consumption
+		assert.Zero(t, bill.Days[2].Consumption)
+		assert.InDelta(t, 60, bill.Total.StandingCharge.ExcVAT, 1e-9)
+		assert.Len(t, bill.Months, 1)
+	}
+
+	// Without a payment method, it's ambiguous which rates apply
+	_, err = Calculate(consumption, tariff, Options{Location: london})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "payment method should be set")
+	}
+
+	// Rates without a payment method apply along with rates of a single one
+	tariff.UnitRates = append(tariff.UnitRates[:1], octopusenergyapi.Rate{ValueExcVAT: 12, ValueIncVAT: 12.6, ValidFrom: date(2020, 7, 1, 12, 0)})
+	bill, err = Calculate(consumption, tariff, Options{Location: london})
+	if assert.Nil(t, err) && assert.Len(t, bill.Days, 1) {
+		// 13 hours at 10p, 11 hours at 12p
+		assert.InDelta(t, 524, bill.Days[0].UnitCost.ExcVAT, 1e-9)
+	}
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	bill, err := Calculate(nil, Tariff{}, Options{})
+	assert.Nil(t, err)
+	assert.Empty(t, bill.Days)
+
+	_, err = Calculate(halfHourly(date(2020, 1, 1, 0, 0), date(2020, 1, 1, 1, 0), 1), Tariff{}, Options{})
+	assert.True(t, errors.Is(err, ErrNoRate))
+}
+
+func TestElecTariff(t *testing.T) {
+	fake := &octopustest.Fake{
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return []octopusenergyapi.Rate{{ValueExcVAT: 10}}, nil
+		},
+		GetElecStandingChargesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return standingCharge, nil
+		},
+	}
+
+	tariff, err := ElecTariff(context.Background(), fake, "VAR-17-01-11", "E-1R-VAR-17-01-11-A", date(2020, 1, 1, 0, 0), date(2020, 2, 1, 0, 0))
+	if assert.Nil(t, err) {
+		assert.Len(t, tariff.UnitRates, 1)
+		assert.Equal(t, standingCharge, tariff.StandingCharges)
+	}
+
+	calls := fake.CallsTo("GetElecStandingChargesContext")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, date(2020, 1, 1, 0, 0), calls[0].Args[2].(octopusenergyapi.RateOption).From)
+	}
+
+	_, err = GasTariff(context.Background(), fake, "VAR-17-01-11", "G-1R-VAR-17-01-11-A", time.Time{}, time.Time{})
+	assert.True(t, errors.Is(err, octopustest.ErrNotProgrammed))
+}
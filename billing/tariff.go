@@ -0,0 +1,45 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// ElecTariff retrieves unit rates and standing charges of a single-register
+// electricity tariff, valid between from and to
+func ElecTariff(ctx context.Context, api octopusenergyapi.API, productCode, tariffCode string, from, to time.Time) (Tariff, error) {
+	options := octopusenergyapi.RateOption{From: from, To: to, PageSize: 1500}
+
+	unitRates, err := api.GetElecStandardUnitRatesContext(ctx, productCode, tariffCode, options)
+	if err != nil {
+		return Tariff{}, errors.Wrap(err, "unable to retrieve unit rates")
+	}
+
+	standingCharges, err := api.GetElecStandingChargesContext(ctx, productCode, tariffCode, options)
+	if err != nil {
+		return Tariff{}, errors.Wrap(err, "unable to retrieve standing charges")
+	}
+
+	return Tariff{UnitRates: unitRates, StandingCharges: standingCharges}, nil
+}
+
+// GasTariff retrieves unit rates and standing charges of a gas tariff,
+// valid between from and to
+func GasTariff(ctx context.Context, api octopusenergyapi.API, productCode, tariffCode string, from, to time.Time) (Tariff, error) {
+	options := octopusenergyapi.RateOption{From: from, To: to, PageSize: 1500}
+
+	unitRates, err := api.GetGasStandardUnitRatesContext(ctx, productCode, tariffCode, options)
+	if err != nil {
+		return Tariff{}, errors.Wrap(err, "unable to retrieve unit rates")
+	}
+
+	standingCharges, err := api.GetGasStandingChargesContext(ctx, productCode, tariffCode, options)
+	if err != nil {
+		return Tariff{}, errors.Wrap(err, "unable to retrieve standing charges")
+	}
+
+	return Tariff{UnitRates: unitRates, StandingCharges: standingCharges}, nil
+}
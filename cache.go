@@ -0,0 +1,247 @@
+package octopusenergyapi
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCacheTTLs caches products, tariffs and grid supply points,
+// which change rarely. Unit rates and standing charges of tariffs are not
+// cached, as rates of some tariffs are published daily.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"products/":                       time.Hour,
+	"products/*/electricity-tariffs/": 0,
+	"products/*/gas-tariffs/":         0,
+	"industry/grid-supply-points/":    24 * time.Hour,
+}
+
+// CacheEntry represents a cached API response
+type CacheEntry struct {
+	Body         []byte
+	ETag         string
+	LastModified string
+	// Expires is the time after which the entry has to be revalidated
+	Expires time.Time
+}
+
+// Cache stores API responses, keyed by request url.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry) error
+}
+
+// WithCache enables caching of responses. ttls maps path prefixes
+// (e.g. "products/") to how long responses are served from the cache
+// before being revalidated; the longest matching prefix is used and other
+// paths are not cached. A "*" in a prefix matches a single path segment,
+// e.g. "products/*/gas-tariffs/". If ttls is nil, DefaultCacheTTLs is used.
+// ttls is copied, so changing it afterwards doesn't affect the client.
+//
+// Cache keys don't include the API key, so a cache should not be shared by
+// clients with different API keys if account specific paths are cached.
+func WithCache(cache Cache, ttls map[string]time.Duration) Option {
+	return func(c *Client) error {
+		if cache == nil {
+			return errors.New("cache should not be nil")
+		}
+
+		if ttls == nil {
+			ttls = DefaultCacheTTLs
+		}
+
+		c.cache = cache
+		c.cacheTTLs = make(map[string]time.Duration, len(ttls))
+		for prefix, ttl := range ttls {
+			c.cacheTTLs[prefix] = ttl
+		}
+		return nil
+	}
+}
+
+// cacheKey returns the cache key of path. Keys include the base URL, so
+// that clients of different servers can share a cache.
+func (c *Client) cacheKey(path string) string {
+	return fmt.Sprintf("%s/%s", c.baseURL, path)
+}
+
+// cacheTTL returns how long a response for path should be cached
+func (c *Client) cacheTTL(path string) time.Duration {
+	if c.cache == nil {
+		return 0
+	}
+
+	var ttl time.Duration
+	longest := -1
+	for prefix, d := range c.cacheTTLs {
+		if hasPathPrefix(path, prefix) && len(prefix) > longest {
+			ttl, longest = d, len(prefix)
+		}
+	}
+
+	return ttl
+}
+
+// hasPathPrefix reports whether path begins with prefix, where "*" in prefix
+// matches a single non-empty path segment
+func hasPathPrefix(path, prefix string) bool {
+	for {
+		i := strings.IndexByte(prefix, '*')
+		if i < 0 {
+			return strings.HasPrefix(path, prefix)
+		}
+		if !strings.HasPrefix(path, prefix[:i]) {
+			return false
+		}
+
+		path, prefix = path[i:], prefix[i+1:]
+		j := strings.IndexAny(path, "/?")
+		if j < 0 {
+			j = len(path)
+		}
+		if j == 0 {
+			return false
+		}
+		path = path[j:]
+	}
+}
+
+// MemoryCache is an in-memory Cache, evicting least recently used entries
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewMemoryCache returns an in-memory cache holding up to size entries
+func NewMemoryCache(size int) *MemoryCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &MemoryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get implements Cache
+func (m *MemoryCache) Get(key string) (CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+
+	m.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).entry, true
+}
+
+// Set implements Cache
+func (m *MemoryCache) Set(key string, entry CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = entry
+		m.order.MoveToFront(el)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryCacheItem{key, entry})
+
+	for m.order.Len() > m.size {
+		el := m.order.Back()
+		m.order.Remove(el)
+		delete(m.entries, el.Value.(*memoryCacheItem).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached entries
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+// DiskCache is a Cache storing entries as files in a directory
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache returns a cache storing entries in dir, creating it if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(err, "unable to create cache directory")
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+// filename returns the name of the file storing an entry
+func (d *DiskCache) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache. Unreadable entries are treated as missing.
+func (d *DiskCache) Get(key string) (CacheEntry, bool) {
+	data, err := os.ReadFile(d.filename(key))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return CacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Set implements Cache
+func (d *DiskCache) Set(key string, entry CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal cache entry")
+	}
+
+	// Write to a temporary file first, so that readers never see partial entries
+	f, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "unable to create cache file")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "unable to write cache file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "unable to write cache file")
+	}
+
+	return errors.Wrap(os.Rename(f.Name(), d.filename(key)), "unable to write cache file")
+}
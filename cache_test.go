@@ -0,0 +1,246 @@
+package octopusenergyapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(2)
+
+	assert.Nil(t, c.Set("a", CacheEntry{Body: []byte("a")}))
+	assert.Nil(t, c.Set("b", CacheEntry{Body: []byte("b")}))
+
+	// Reading "a" makes "b" the least recently used entry
+	entry, ok := c.Get("a")
+	if assert.True(t, ok) {
+		assert.Equal(t, []byte("a"), entry.Body)
+	}
+
+	assert.Nil(t, c.Set("c", CacheEntry{Body: []byte("c")}))
+	assert.Equal(t, 2, c.Len())
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+
+	// Update existing entry
+	assert.Nil(t, c.Set("c", CacheEntry{Body: []byte("updated")}))
+	entry, ok = c.Get("c")
+	if assert.True(t, ok) {
+		assert.Equal(t, []byte("updated"), entry.Body)
+	}
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestDiskCache(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, ok := c.Get("products/")
+	assert.False(t, ok)
+
+	expires := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := CacheEntry{
+		Body:         []byte(`{"count":0}`),
+		ETag:         `"abc"`,
+		LastModified: "Sat, 01 Jan 2022 00:00:00 GMT",
+		Expires:      expires,
+	}
+	assert.Nil(t, c.Set("products/", entry))
+
+	got, ok := c.Get("products/")
+	if assert.True(t, ok) {
+		assert.Equal(t, entry.Body, got.Body)
+		assert.Equal(t, entry.ETag, got.ETag)
+		assert.Equal(t, entry.LastModified, got.LastModified)
+		assert.True(t, expires.Equal(got.Expires))
+	}
+
+	_, ok = c.Get("products/?page=2")
+	assert.False(t, ok)
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, err := NewClient("fakeapikey", nil, WithCache(NewMemoryCache(10), map[string]time.Duration{
+		"products/":        time.Hour,
+		"products/AGILE-":  time.Minute,
+		"accounts/":        0,
+		"industry/unknown": time.Second,
+	}))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, time.Hour, c.cacheTTL("products/?is_green=true"))
+	assert.Equal(t, time.Minute, c.cacheTTL("products/AGILE-18-02-21/"))
+	assert.Equal(t, time.Duration(0), c.cacheTTL("accounts/A-1234ABCD/"))
+	assert.Equal(t, time.Duration(0), c.cacheTTL("electricity-meter-points/0123456789/"))
+
+	// Charges of tariffs are not cached by default
+	c, err = NewClient("fakeapikey", nil, WithCache(NewMemoryCache(10), nil))
+	if assert.Nil(t, err) {
+		assert.Equal(t, time.Hour, c.cacheTTL("products/"))
+		assert.Equal(t, time.Hour, c.cacheTTL("products/AGILE-18-02-21/?tariffs_active_at=2020-11-28T00:00:00Z"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("products/AGILE-18-02-21/electricity-tariffs/E-1R-AGILE-18-02-21-C/standard-unit-rates/"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("products/VAR-17-01-11/electricity-tariffs/E-2R-VAR-17-01-11-A/night-unit-rates/?page=2"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("products/FIX-12M-20-09-21/gas-tariffs/G-1R-FIX-12M-20-09-21-C/standing-charges/"))
+		assert.Equal(t, 24*time.Hour, c.cacheTTL("industry/grid-supply-points/?postcode=SW1A1AA"))
+	}
+
+	// TTLs are copied
+	ttls := map[string]time.Duration{"products/": time.Hour}
+	c, err = NewClient("fakeapikey", nil, WithCache(NewMemoryCache(10), ttls))
+	if assert.Nil(t, err) {
+		ttls["products/"] = time.Minute
+		ttls["industry/"] = time.Minute
+		assert.Equal(t, time.Hour, c.cacheTTL("products/"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("industry/grid-supply-points/"))
+	}
+
+	c, err = NewClient("fakeapikey", nil, WithCache(NewMemoryCache(10), map[string]time.Duration{
+		"products/*/electricity-tariffs/*/standard-unit-rates/": time.Minute,
+	}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, time.Minute, c.cacheTTL("products/AGILE-18-02-21/electricity-tariffs/E-1R-AGILE-18-02-21-C/standard-unit-rates/"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("products/AGILE-18-02-21/electricity-tariffs/E-1R-AGILE-18-02-21-C/standing-charges/"))
+		assert.Equal(t, time.Duration(0), c.cacheTTL("products//electricity-tariffs/E-1R-AGILE-18-02-21-C/standard-unit-rates/"))
+	}
+
+	_, err = NewClient("fakeapikey", nil, WithCache(nil, nil))
+	assert.NotNil(t, err)
+}
+
+func TestClientCache(t *testing.T) {
+	t.Run("fresh", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			_, err := w.Write([]byte(`{"code":"VAR-17-01-11","gsp":"_A"}`))
+			assert.Nil(t, err)
+		}))
+		defer server.Close()
+
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL), WithCache(NewMemoryCache(10), nil))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		for i := 0; i < 3; i++ {
+			product, err := client.GetProduct("VAR-17-01-11")
+			if assert.Nil(t, err) {
+				assert.Equal(t, "VAR-17-01-11", product.Code)
+			}
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+		// Meter points are not cached by default
+		for i := 0; i < 2; i++ {
+			_, err := client.GetMeterPoint("0123456789")
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+		// Nor are unit rates of tariffs
+		for i := 0; i < 2; i++ {
+			_, err := client.GetElecStandardUnitRates("VAR-17-01-11", "E-1R-VAR-17-01-11-A", RateOption{})
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, int32(5), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("revalidate", func(t *testing.T) {
+		var requests, notModified int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			_, err := w.Write([]byte(`{"code":"VAR-17-01-11"}`))
+			assert.Nil(t, err)
+		}))
+		defer server.Close()
+
+		// Entries expire immediately, so every request is revalidated
+		cache := NewMemoryCache(10)
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL), WithCache(cache, map[string]time.Duration{
+			"products/": time.Nanosecond,
+		}))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		for i := 0; i < 3; i++ {
+			product, err := client.GetProduct("VAR-17-01-11")
+			if assert.Nil(t, err) {
+				assert.Equal(t, "VAR-17-01-11", product.Code)
+			}
+		}
+		assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&notModified))
+
+		entry, ok := cache.Get(server.URL + "/products/VAR-17-01-11/")
+		if assert.True(t, ok) {
+			assert.Equal(t, `"v1"`, entry.ETag)
+		}
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		// Responses of different servers are cached separately
+		cache := NewMemoryCache(10)
+		for _, code := range []string{"VAR-17-01-11", "AGILE-18-02-21"} {
+			code := code
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(`{"code":"` + code + `"}`))
+				assert.Nil(t, err)
+			}))
+			defer server.Close()
+
+			client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL), WithCache(cache, nil))
+			if assert.Nil(t, err) {
+				product, err := client.GetProduct("PRODUCT")
+				if assert.Nil(t, err) {
+					assert.Equal(t, code, product.Code)
+				}
+			}
+		}
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("errors_not_cached", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		cache := NewMemoryCache(10)
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL), WithCache(cache, nil))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetProduct("UNKNOWN")
+			assert.NotNil(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+		assert.Equal(t, 0, cache.Len())
+	})
+}
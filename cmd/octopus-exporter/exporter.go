@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// Fuels, as used in metric labels
+const (
+	fuelElectricity = "electricity"
+	fuelGas         = "gas"
+)
+
+// ratePeriod is the shortest period of unit rates. Tariff charges are
+// requested from the start of the current period, so that requests within
+// a period are the same and can be served from the cache.
+const ratePeriod = 30 * time.Minute
+
+// Directions of consumption, as used in metric labels. Consumption of
+// export meters is exported energy.
+const (
+	directionImport = "import"
+	directionExport = "export"
+)
+
+// backfillWindow is how long before the latest interval consumption is
+// requested again. Octopus often publishes intervals late and out of order,
+// so gaps filled within the window are still counted.
+const backfillWindow = 48 * time.Hour
+
+// Registers of unit rates, as used in metric labels
+const (
+	registerStandard = "standard"
+	registerDay      = "day"
+	registerNight    = "night"
+)
+
+// meterKey identifies a meter
+type meterKey struct {
+	fuel       string
+	direction  string
+	meterPoint string
+	serial     string
+}
+
+// meterState is consumption collected from a meter
+type meterState struct {
+	// total is consumption since the start of collection
+	total float64
+	// latest is the most recent consumption interval
+	latest octopusenergyapi.Consumption
+	// counted holds start times, in Unix seconds, of intervals included
+	// in total, within the backfill window
+	counted map[int64]bool
+}
+
+// backfillFrom returns the start of consumption requested from a meter
+func (s meterState) backfillFrom(since time.Time) time.Time {
+	if from := s.latest.IntervalEnd.Add(-backfillWindow); from.After(since) {
+		return from
+	}
+
+	return since
+}
+
+// tariffKey identifies a tariff
+type tariffKey struct {
+	fuel       string
+	tariffCode string
+}
+
+// tariffState holds prices of a tariff. Unit rates are keyed by register
+// and payment method, standing charges by payment method.
+type tariffState struct {
+	current  map[string]map[string]octopusenergyapi.Rate
+	next     map[string]map[string]octopusenergyapi.Rate
+	standing map[string]octopusenergyapi.Rate
+	// updated is the time of the poll which retrieved the prices
+	updated time.Time
+}
+
+// unitRatesFunc retrieves unit rates of a register of a tariff
+type unitRatesFunc func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+
+// exporter polls the API and serves the latest results as metrics
+type exporter struct {
+	api     octopusenergyapi.API
+	account string
+	// since is the start of consumption collection
+	since  time.Time
+	health *transport
+	logger octopusenergyapi.Logger
+	now    func() time.Time
+
+	mu          sync.RWMutex
+	meters      map[meterKey]meterState
+	tariffs     map[tariffKey]tariffState
+	polls       int
+	pollErrors  int
+	lastSuccess time.Time
+}
+
+func newExporter(api octopusenergyapi.API, account string, since time.Time, health *transport, logger octopusenergyapi.Logger) *exporter {
+	return &exporter{
+		api:     api,
+		account: account,
+		since:   since,
+		health:  health,
+		logger:  logger,
+		now:     time.Now,
+		meters:  make(map[meterKey]meterState),
+		tariffs: make(map[tariffKey]tariffState),
+	}
+}
+
+// run polls the API every interval until ctx is cancelled
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.poll(ctx); err != nil && ctx.Err() == nil {
+			e.logger.Printf("poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll retrieves consumption of meters of the account and prices of their
+// active tariffs. A failure of one meter or tariff does not prevent the
+// others from being updated, and prices of a tariff which fail to be
+// retrieved are kept from the previous poll; the first error is returned.
+func (e *exporter) poll(ctx context.Context) error {
+	now := e.now()
+
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	account, err := e.api.GetAccountContext(ctx, e.account)
+	if err != nil {
+		err = errors.Wrap(err, "unable to retrieve account")
+		e.finish(now, nil, nil, nil, err)
+		return err
+	}
+
+	meters := make(map[meterKey]bool)
+	collect := func(key meterKey) {
+		meters[key] = true
+		if err := e.collect(ctx, key); err != nil {
+			fail(err)
+		}
+	}
+
+	active := make(map[tariffKey]bool)
+	tariffs := make(map[tariffKey]tariffState)
+	addTariff := func(fuel string, agreements []octopusenergyapi.Agreement) {
+		for _, a := range agreements {
+			key := tariffKey{fuel, a.TariffCode}
+			if active[key] || !a.Active(now) {
+				continue
+			}
+			active[key] = true
+
+			state, err := e.tariff(ctx, fuel, a, now)
+			if err != nil {
+				fail(errors.Wrapf(err, "tariff %s", a.TariffCode))
+				continue
+			}
+			tariffs[key] = state
+		}
+	}
+
+	for _, p := range account.Properties {
+		if !p.MovedOutAt.IsZero() && p.MovedOutAt.Before(now) {
+			continue
+		}
+
+		for _, mp := range p.ElecMeterPoints {
+			direction := directionImport
+			if mp.IsExport {
+				direction = directionExport
+			}
+
+			for _, m := range mp.Meters {
+				collect(meterKey{fuelElectricity, direction, mp.MPAN, m.SerialNumber})
+			}
+			addTariff(fuelElectricity, mp.Agreements)
+		}
+
+		for _, mp := range p.GasMeterPoints {
+			for _, m := range mp.Meters {
+				collect(meterKey{fuelGas, directionImport, mp.MPRN, m.SerialNumber})
+			}
+			addTariff(fuelGas, mp.Agreements)
+		}
+	}
+
+	e.finish(now, meters, active, tariffs, firstErr)
+	return firstErr
+}
+
+// finish records the result of a poll. Unless the account failed to be
+// retrieved, meters which are no longer in the account and tariffs which are
+// no longer active are removed, and the retrieved tariffs are updated.
+func (e *exporter) finish(now time.Time, meters map[meterKey]bool, active map[tariffKey]bool, tariffs map[tariffKey]tariffState, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.polls++
+	if err != nil {
+		e.pollErrors++
+	} else {
+		e.lastSuccess = now
+	}
+
+	if active == nil {
+		return
+	}
+	for key := range e.meters {
+		if !meters[key] {
+			delete(e.meters, key)
+		}
+	}
+	for key := range e.tariffs {
+		if !active[key] {
+			delete(e.tariffs, key)
+		}
+	}
+	for key, state := range tariffs {
+		e.tariffs[key] = state
+	}
+}
+
+// collect adds consumption recorded since the previous poll to a meter,
+// including intervals published late within the backfill window
+func (e *exporter) collect(ctx context.Context, key meterKey) error {
+	e.mu.RLock()
+	state := e.meters[key]
+	e.mu.RUnlock()
+
+	from := state.backfillFrom(e.since)
+	options := octopusenergyapi.ConsumptionOption{From: from, PageSize: 25000}
+
+	var consumption []octopusenergyapi.Consumption
+	var err error
+	if key.fuel == fuelGas {
+		consumption, err = e.api.GetGasMeterConsumptionContext(ctx, key.meterPoint, key.serial, options)
+	} else {
+		consumption, err = e.api.GetElecMeterConsumptionContext(ctx, key.meterPoint, key.serial, options)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to retrieve consumption of meter %s", key.serial)
+	}
+
+	counted := make(map[int64]bool, len(state.counted))
+	for start := range state.counted {
+		counted[start] = true
+	}
+
+	for _, c := range consumption {
+		// Intervals in the backfill window are returned by every poll
+		if counted[c.IntervalStart.Unix()] {
+			continue
+		}
+		counted[c.IntervalStart.Unix()] = true
+
+		state.total += decimal(c.Value)
+		if c.IntervalEnd.After(state.latest.IntervalEnd) {
+			state.latest = c
+		}
+	}
+
+	// Intervals before the window won't be requested again
+	from = state.backfillFrom(e.since)
+	for start := range counted {
+		if start < from.Unix() {
+			delete(counted, start)
+		}
+	}
+	state.counted = counted
+
+	e.mu.Lock()
+	e.meters[key] = state
+	e.mu.Unlock()
+
+	return nil
+}
+
+// tariff retrieves current and next unit rates of each register and the
+// standing charge of an agreement
+func (e *exporter) tariff(ctx context.Context, fuel string, agreement octopusenergyapi.Agreement, now time.Time) (tariffState, error) {
+	productCode := agreement.ProductCode()
+	if productCode == "" {
+		return tariffState{}, errors.New("invalid tariff code")
+	}
+
+	from := now.Truncate(ratePeriod)
+	options := octopusenergyapi.RateOption{From: from, To: from.Add(24 * time.Hour), PageSize: 1500}
+
+	var registers []string
+	var unitRates []unitRatesFunc
+	switch {
+	case fuel == fuelGas:
+		registers = []string{registerStandard}
+		unitRates = []unitRatesFunc{e.api.GetGasStandardUnitRatesContext}
+	case strings.Contains(agreement.TariffCode, "-2R-"):
+		registers = []string{registerDay, registerNight}
+		unitRates = []unitRatesFunc{e.api.GetElecDayUnitRatesContext, e.api.GetElecNightUnitRatesContext}
+	default:
+		registers = []string{registerStandard}
+		unitRates = []unitRatesFunc{e.api.GetElecStandardUnitRatesContext}
+	}
+
+	state := tariffState{
+		current: make(map[string]map[string]octopusenergyapi.Rate),
+		next:    make(map[string]map[string]octopusenergyapi.Rate),
+		updated: now,
+	}
+	for i, register := range registers {
+		rates, err := unitRates[i](ctx, productCode, agreement.TariffCode, options)
+		if err != nil {
+			return tariffState{}, errors.Wrapf(err, "unable to retrieve %s unit rates", register)
+		}
+
+		state.current[register], state.next[register] = ratesAt(rates, now)
+	}
+
+	var standingCharges []octopusenergyapi.Rate
+	var err error
+	if fuel == fuelGas {
+		standingCharges, err = e.api.GetGasStandingChargesContext(ctx, productCode, agreement.TariffCode, options)
+	} else {
+		standingCharges, err = e.api.GetElecStandingChargesContext(ctx, productCode, agreement.TariffCode, options)
+	}
+	if err != nil {
+		return tariffState{}, errors.Wrap(err, "unable to retrieve standing charges")
+	}
+
+	state.standing, _ = ratesAt(standingCharges, now)
+
+	return state, nil
+}
+
+// ratesAt returns rates valid at t and the earliest rates starting after t,
+// for each payment method
+func ratesAt(rates []octopusenergyapi.Rate, t time.Time) (current, next map[string]octopusenergyapi.Rate) {
+	current = make(map[string]octopusenergyapi.Rate)
+	next = make(map[string]octopusenergyapi.Rate)
+
+	for _, r := range rates {
+		if r.ValidFrom.After(t) {
+			if n, ok := next[r.PaymentMethod]; !ok || r.ValidFrom.Before(n.ValidFrom) {
+				next[r.PaymentMethod] = r
+			}
+			continue
+		}
+
+		if !r.ValidTo.IsZero() && !r.ValidTo.After(t) {
+			continue
+		}
+		// The most recent rate applies if more rates are valid
+		if c, ok := current[r.PaymentMethod]; !ok || r.ValidFrom.After(c.ValidFrom) {
+			current[r.PaymentMethod] = r
+		}
+	}
+
+	return current, next
+}
+
+// ServeHTTP writes metrics of the latest poll
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	if err := writeMetrics(w, e.metrics()); err != nil {
+		e.logger.Printf("unable to write metrics: %v", err)
+	}
+}
+
+// metrics returns metrics of the latest poll and of the client
+func (e *exporter) metrics() []*metric {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	latest := &metric{name: "octopus_consumption_latest", help: "Consumption in the latest half-hour, in kWh (m³ for SMETS2 gas meters).", kind: "gauge"}
+	latestTime := &metric{name: "octopus_consumption_latest_timestamp_seconds", help: "End of the latest consumption interval.", kind: "gauge"}
+	total := &metric{name: "octopus_consumption_total", help: "Consumption since the start of collection, in kWh (m³ for SMETS2 gas meters).", kind: "counter"}
+
+	meters := make([]meterKey, 0, len(e.meters))
+	for key := range e.meters {
+		meters = append(meters, key)
+	}
+	sort.Slice(meters, func(i, j int) bool {
+		a, b := meters[i], meters[j]
+		if a.fuel != b.fuel {
+			return a.fuel < b.fuel
+		}
+		if a.direction != b.direction {
+			return a.direction < b.direction
+		}
+		if a.meterPoint != b.meterPoint {
+			return a.meterPoint < b.meterPoint
+		}
+		return a.serial < b.serial
+	})
+
+	for _, key := range meters {
+		state := e.meters[key]
+		labels := []string{"fuel", key.fuel, "direction", key.direction, "meter_point", key.meterPoint, "serial_number", key.serial}
+
+		total.add(state.total, labels...)
+		if !state.latest.IntervalEnd.IsZero() {
+			latest.add(decimal(state.latest.Value), labels...)
+			latestTime.add(float64(state.latest.IntervalEnd.Unix()), labels...)
+		}
+	}
+
+	unitRate := &metric{name: "octopus_unit_rate_pence_per_kwh", help: "Unit rate of each register including VAT, currently and in the next period.", kind: "gauge"}
+	standing := &metric{name: "octopus_standing_charge_pence_per_day", help: "Current standing charge including VAT.", kind: "gauge"}
+	updated := &metric{name: "octopus_tariff_last_update_timestamp_seconds", help: "Time of the latest poll which retrieved prices of the tariff.", kind: "gauge"}
+
+	tariffs := make([]tariffKey, 0, len(e.tariffs))
+	for key := range e.tariffs {
+		tariffs = append(tariffs, key)
+	}
+	sort.Slice(tariffs, func(i, j int) bool {
+		if tariffs[i].fuel != tariffs[j].fuel {
+			return tariffs[i].fuel < tariffs[j].fuel
+		}
+		return tariffs[i].tariffCode < tariffs[j].tariffCode
+	})
+
+	for _, key := range tariffs {
+		state := e.tariffs[key]
+		add := func(m *metric, rates map[string]octopusenergyapi.Rate, extra ...string) {
+			for _, method := range sortedKeys(rates) {
+				labels := append([]string{"fuel", key.fuel, "tariff_code", key.tariffCode, "payment_method", method}, extra...)
+				m.add(decimal(rates[method].ValueIncVAT), labels...)
+			}
+		}
+
+		for _, register := range sortedKeys(state.current) {
+			add(unitRate, state.current[register], "register", register, "period", "current")
+			add(unitRate, state.next[register], "register", register, "period", "next")
+		}
+		add(standing, state.standing)
+		updated.add(float64(state.updated.Unix()), "fuel", key.fuel, "tariff_code", key.tariffCode)
+	}
+
+	polls := &metric{name: "octopus_exporter_polls_total", help: "Polls of the Octopus Energy API.", kind: "counter"}
+	polls.add(float64(e.polls))
+	pollErrors := &metric{name: "octopus_exporter_poll_errors_total", help: "Polls which failed, fully or partially.", kind: "counter"}
+	pollErrors.add(float64(e.pollErrors))
+	lastSuccess := &metric{name: "octopus_exporter_last_success_timestamp_seconds", help: "Time of the latest successful poll.", kind: "gauge"}
+	if !e.lastSuccess.IsZero() {
+		lastSuccess.add(float64(e.lastSuccess.Unix()))
+	}
+
+	metrics := []*metric{latest, latestTime, total, unitRate, standing, updated, polls, pollErrors, lastSuccess}
+	if e.health != nil {
+		metrics = append(metrics, e.health.metrics()...)
+	}
+
+	return metrics
+}
+
+// decimal converts f to float64 by its shortest decimal representation,
+// e.g. 0.1 instead of 0.10000000149011612
+func decimal(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return v
+}
+
+// sortedKeys returns keys of m in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
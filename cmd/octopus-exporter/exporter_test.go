@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/internal/fixture"
+	"github.com/FileGo/octopusenergyapi/octopustest"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestExporter returns an exporter of the test account at 2020-11-28 12:00
+func newTestExporter(t *testing.T, server *octopustest.Server, options ...octopusenergyapi.Option) *exporter {
+	health := &transport{next: server.Client().Transport}
+	options = append([]octopusenergyapi.Option{octopusenergyapi.WithBaseURL(server.BaseURL())}, options...)
+	client, err := octopusenergyapi.NewClient(server.APIKey, &http.Client{Transport: health}, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newExporter(client, "A-1234ABCD", time.Date(2020, 11, 27, 0, 0, 0, 0, time.UTC), health, log.New(io.Discard, "", 0))
+	e.now = func() time.Time {
+		return time.Date(2020, 11, 28, 12, 0, 0, 0, time.UTC)
+	}
+
+	return e
+}
+
+// scrape returns metrics served by h
+func scrape(t *testing.T, h http.Handler) string {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, contentType, rec.Header().Get("Content-Type"))
+
+	return rec.Body.String()
+}
+
+// value returns the value of a series in metrics, or NaN if not found
+func value(t *testing.T, metrics, series string) float64 {
+	for _, line := range strings.Split(metrics, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			f, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			assert.Nil(t, err)
+			return f
+		}
+	}
+
+	return math.NaN()
+}
+
+func TestExporter(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server)
+	assert.Nil(t, e.poll(context.Background()))
+
+	metrics := scrape(t, e)
+	assert.Contains(t, metrics, "# TYPE octopus_consumption_total counter\n")
+
+	elec := `{fuel="electricity",direction="import",meter_point="0123456789012",serial_number="19L0123456"}`
+	gas := `{fuel="gas",direction="import",meter_point="1234567890",serial_number="G4A01234567890"}`
+	agile := `{fuel="electricity",tariff_code="E-1R-AGILE-18-02-21-C",payment_method=""`
+	fixed := `{fuel="gas",tariff_code="G-1R-FIX-12M-20-09-21-C",payment_method="DIRECT_DEBIT"`
+	standard := `,register="standard"`
+
+	for series, expected := range map[string]float64{
+		"octopus_consumption_total" + elec:                                          31.15,
+		"octopus_consumption_total" + gas:                                           19.728,
+		"octopus_consumption_latest" + gas:                                          0.084,
+		"octopus_consumption_latest_timestamp_seconds" + elec:                       1606608000,
+		"octopus_unit_rate_pence_per_kwh" + agile + standard + `,period="current"}`: 13.65,
+		"octopus_unit_rate_pence_per_kwh" + agile + standard + `,period="next"}`:    14.3325,
+		"octopus_unit_rate_pence_per_kwh" + fixed + standard + `,period="current"}`: 2.625,
+		"octopus_standing_charge_pence_per_day" + agile + "}":                       21,
+		"octopus_standing_charge_pence_per_day" + fixed + "}":                       17.85,
+		"octopus_exporter_polls_total":                                              1,
+		"octopus_exporter_poll_errors_total":                                        0,
+		"octopus_exporter_last_success_timestamp_seconds":                           1606564800,
+		"octopus_api_requests_total":                                                7,
+		"octopus_api_request_errors_total":                                          0,
+		"octopus_api_request_duration_seconds_count":                                7,
+	} {
+		assert.InDelta(t, expected, value(t, metrics, series), 1e-9, series)
+	}
+
+	// Fixed tariff has no next rate, expired agreement is left out
+	assert.NotContains(t, metrics, `tariff_code="G-1R-FIX-12M-20-09-21-C",payment_method="DIRECT_DEBIT",register="standard",period="next"`)
+	assert.NotContains(t, metrics, "VAR-17-01-11")
+
+	// Consumption is not counted twice
+	assert.Nil(t, e.poll(context.Background()))
+	metrics = scrape(t, e)
+	assert.InDelta(t, 31.15, value(t, metrics, "octopus_consumption_total"+elec), 1e-9)
+	assert.InDelta(t, 2, value(t, metrics, "octopus_exporter_polls_total"), 1e-9)
+}
+
+func TestExporterBackfill(t *testing.T) {
+	server := octopustest.NewEmptyServer()
+	defer server.Close()
+
+	server.AddAccount(octopusenergyapi.Account{
+		Number: "A-1234ABCD",
+		Properties: []octopusenergyapi.Property{{
+			ElecMeterPoints: []octopusenergyapi.AccountElecMeterPoint{{
+				MPAN:   "0123456789012",
+				Meters: []octopusenergyapi.Meter{{SerialNumber: "19L0123456"}},
+			}},
+		}},
+	})
+
+	// Interval at 02:00 is published late
+	consumption := fixture.HalfHourly(fixture.Date(2020, 11, 28, 0, 0), fixture.Date(2020, 11, 28, 6, 0), 1)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789012", "19L0123456", append(consumption[:4:4], consumption[5:]...))
+
+	e := newTestExporter(t, server)
+	series := `octopus_consumption_total{fuel="electricity",direction="import",meter_point="0123456789012",serial_number="19L0123456"}`
+	assert.Nil(t, e.poll(context.Background()))
+	assert.InDelta(t, 11, value(t, scrape(t, e), series), 1e-9)
+
+	consumption[4].Value = 5
+	consumption = append(consumption, fixture.HalfHourly(fixture.Date(2020, 11, 28, 6, 0), fixture.Date(2020, 11, 28, 6, 30), 1)...)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789012", "19L0123456", consumption)
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, e.poll(context.Background()))
+		assert.InDelta(t, 17, value(t, scrape(t, e), series), 1e-9)
+	}
+}
+
+func TestExporterExportMeter(t *testing.T) {
+	server := octopustest.NewEmptyServer()
+	defer server.Close()
+
+	meters := []octopusenergyapi.Meter{{SerialNumber: "19L0123456"}}
+	server.AddAccount(octopusenergyapi.Account{
+		Number: "A-1234ABCD",
+		Properties: []octopusenergyapi.Property{{
+			ElecMeterPoints: []octopusenergyapi.AccountElecMeterPoint{
+				{MPAN: "0123456789012", Meters: meters},
+				{MPAN: "0123456789099", Meters: meters, IsExport: true},
+			},
+		}},
+	})
+	period := fixture.HalfHourly(fixture.Date(2020, 11, 28, 0, 0), fixture.Date(2020, 11, 28, 1, 0), 1)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789012", "19L0123456", period)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789099", "19L0123456", fixture.HalfHourly(period[0].IntervalStart, period[1].IntervalEnd, 3))
+
+	e := newTestExporter(t, server)
+	assert.Nil(t, e.poll(context.Background()))
+
+	metrics := scrape(t, e)
+	assert.InDelta(t, 2, value(t, metrics, `octopus_consumption_total{fuel="electricity",direction="import",meter_point="0123456789012",serial_number="19L0123456"}`), 1e-9)
+	assert.InDelta(t, 6, value(t, metrics, `octopus_consumption_total{fuel="electricity",direction="export",meter_point="0123456789099",serial_number="19L0123456"}`), 1e-9)
+}
+
+func TestExporterRemovedMeter(t *testing.T) {
+	server := octopustest.NewEmptyServer()
+	defer server.Close()
+
+	account := func(serials ...string) octopusenergyapi.Account {
+		var meters []octopusenergyapi.Meter
+		for _, serial := range serials {
+			meters = append(meters, octopusenergyapi.Meter{SerialNumber: serial})
+		}
+		return octopusenergyapi.Account{
+			Number:     "A-1234ABCD",
+			Properties: []octopusenergyapi.Property{{ElecMeterPoints: []octopusenergyapi.AccountElecMeterPoint{{MPAN: "0123456789012", Meters: meters}}}},
+		}
+	}
+	period := fixture.HalfHourly(fixture.Date(2020, 11, 28, 0, 0), fixture.Date(2020, 11, 28, 1, 0), 1)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789012", "19L0123456", period)
+	server.SetConsumption(octopustest.FuelElectricity, "0123456789012", "21L0123456", period)
+
+	old := `octopus_consumption_total{fuel="electricity",direction="import",meter_point="0123456789012",serial_number="19L0123456"}`
+	replacement := `octopus_consumption_total{fuel="electricity",direction="import",meter_point="0123456789012",serial_number="21L0123456"}`
+
+	server.AddAccount(account("19L0123456"))
+	e := newTestExporter(t, server)
+	assert.Nil(t, e.poll(context.Background()))
+	assert.InDelta(t, 2, value(t, scrape(t, e), old), 1e-9)
+
+	// The meter is replaced
+	server.AddAccount(account("21L0123456"))
+	assert.Nil(t, e.poll(context.Background()))
+	metrics := scrape(t, e)
+	assert.NotContains(t, metrics, `serial_number="19L0123456"`)
+	assert.InDelta(t, 2, value(t, metrics, replacement), 1e-9)
+
+	// Meters are kept when the account fails to be retrieved
+	e.account = "A-0000AAAA"
+	assert.NotNil(t, e.poll(context.Background()))
+	assert.InDelta(t, 2, value(t, scrape(t, e), replacement), 1e-9)
+}
+
+func TestExporterStaleTariff(t *testing.T) {
+	agreement := octopusenergyapi.Agreement{TariffCode: "E-1R-AGILE-18-02-21-C", ValidFrom: fixture.Date(2020, 1, 1, 0, 0)}
+	account := octopusenergyapi.Account{
+		Number: "A-1234ABCD",
+		Properties: []octopusenergyapi.Property{{
+			ElecMeterPoints: []octopusenergyapi.AccountElecMeterPoint{{MPAN: "0123456789012", Agreements: []octopusenergyapi.Agreement{agreement}}},
+		}},
+	}
+	rates := []octopusenergyapi.Rate{{ValueExcVAT: 20, ValueIncVAT: 21, ValidFrom: fixture.Date(2020, 1, 1, 0, 0)}}
+
+	fake := &octopustest.Fake{
+		GetAccountFunc: func(ctx context.Context, accountNumber string) (octopusenergyapi.Account, error) {
+			return account, nil
+		},
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return rates, nil
+		},
+		GetElecStandingChargesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return rates, nil
+		},
+	}
+
+	e := newExporter(fake, "A-1234ABCD", fixture.Date(2020, 11, 27, 0, 0), nil, log.New(io.Discard, "", 0))
+	e.now = func() time.Time { return fixture.Date(2020, 11, 28, 12, 0) }
+	assert.Nil(t, e.poll(context.Background()))
+
+	// Prices are kept when they fail to be retrieved
+	fake.GetElecStandingChargesFunc = nil
+	e.now = func() time.Time { return fixture.Date(2020, 11, 28, 12, 30) }
+	assert.NotNil(t, e.poll(context.Background()))
+
+	metrics := scrape(t, e)
+	agile := `{fuel="electricity",tariff_code="E-1R-AGILE-18-02-21-C"`
+	assert.InDelta(t, 21, value(t, metrics, "octopus_unit_rate_pence_per_kwh"+agile+`,payment_method="",register="standard",period="current"}`), 1e-9)
+	assert.InDelta(t, 21, value(t, metrics, "octopus_standing_charge_pence_per_day"+agile+`,payment_method=""}`), 1e-9)
+	assert.InDelta(t, 1606564800, value(t, metrics, "octopus_tariff_last_update_timestamp_seconds"+agile+"}"), 1e-9)
+
+	// Tariffs which are no longer active are removed
+	account.Properties[0].ElecMeterPoints[0].Agreements[0].ValidTo = fixture.Date(2020, 11, 28, 0, 0)
+	assert.Nil(t, e.poll(context.Background()))
+	assert.NotContains(t, scrape(t, e), "AGILE")
+}
+
+func TestExporterCache(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server, octopusenergyapi.WithCache(octopusenergyapi.NewMemoryCache(100), cacheTTLs))
+	assert.Nil(t, e.poll(context.Background()))
+	assert.InDelta(t, 7, value(t, scrape(t, e), "octopus_api_requests_total"), 1e-9)
+
+	// Only consumption of the two meters is retrieved again within the period
+	e.now = func() time.Time {
+		return time.Date(2020, 11, 28, 12, 15, 0, 0, time.UTC)
+	}
+	assert.Nil(t, e.poll(context.Background()))
+	metrics := scrape(t, e)
+	assert.InDelta(t, 9, value(t, metrics, "octopus_api_requests_total"), 1e-9)
+	assert.InDelta(t, 13.65, value(t, metrics, `octopus_unit_rate_pence_per_kwh{fuel="electricity",tariff_code="E-1R-AGILE-18-02-21-C",payment_method="",register="standard",period="current"}`), 1e-9)
+
+	// Charges of the next period are requested again
+	e.now = func() time.Time {
+		return time.Date(2020, 11, 28, 12, 30, 0, 0, time.UTC)
+	}
+	assert.Nil(t, e.poll(context.Background()))
+	assert.InDelta(t, 15, value(t, scrape(t, e), "octopus_api_requests_total"), 1e-9)
+}
+
+func TestExporterDualRegister(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	server.AddAccount(octopusenergyapi.Account{
+		Number: "A-1234ABCD",
+		Properties: []octopusenergyapi.Property{{
+			ElecMeterPoints: []octopusenergyapi.AccountElecMeterPoint{{
+				MPAN: "0123456789012",
+				Agreements: []octopusenergyapi.Agreement{{
+					TariffCode: "E-2R-VAR-17-01-11-A",
+					ValidFrom:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}},
+			}},
+		}},
+	})
+	server.SetCharges(octopustest.FuelElectricity, "VAR-17-01-11", "E-2R-VAR-17-01-11-A", octopustest.ChargeStandingCharges, []octopusenergyapi.Rate{
+		{ValueExcVAT: 20, ValueIncVAT: 21, ValidFrom: time.Date(2017, 1, 11, 0, 0, 0, 0, time.UTC), PaymentMethod: "DIRECT_DEBIT"},
+	})
+
+	e := newTestExporter(t, server)
+	assert.Nil(t, e.poll(context.Background()))
+
+	metrics := scrape(t, e)
+	dual := `{fuel="electricity",tariff_code="E-2R-VAR-17-01-11-A",payment_method="DIRECT_DEBIT"`
+
+	for series, expected := range map[string]float64{
+		"octopus_unit_rate_pence_per_kwh" + dual + `,register="day",period="current"}`:   17.7135,
+		"octopus_unit_rate_pence_per_kwh" + dual + `,register="night",period="current"}`: 10.4055,
+		"octopus_standing_charge_pence_per_day" + dual + "}":                             21,
+		"octopus_exporter_poll_errors_total":                                             0,
+	} {
+		assert.InDelta(t, expected, value(t, metrics, series), 1e-9, series)
+	}
+	assert.NotContains(t, metrics, `register="standard"`)
+}
+
+func TestExporterErrors(t *testing.T) {
+	server := octopustest.NewEmptyServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server)
+	assert.NotNil(t, e.poll(context.Background()))
+
+	metrics := scrape(t, e)
+	assert.Contains(t, metrics, "octopus_exporter_poll_errors_total 1\n")
+	assert.Contains(t, metrics, "octopus_api_request_errors_total 1\n")
+	assert.NotContains(t, metrics, "octopus_exporter_last_success_timestamp_seconds")
+}
+
+func TestWriteMetrics(t *testing.T) {
+	m := &metric{name: "test_metric", help: "Help with \\ and\nnewline.", kind: "gauge"}
+	m.add(1.5, "label", "a \"quoted\"\nvalue")
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeMetrics(&buf, []*metric{m, {name: "empty", kind: "gauge"}}))
+	assert.Equal(t, "# HELP test_metric Help with \\\\ and\\nnewline.\n"+
+		"# TYPE test_metric gauge\n"+
+		"test_metric{label=\"a \\\"quoted\\\"\\nvalue\"} 1.5\n", buf.String())
+
+	assert.Equal(t, io.ErrClosedPipe, writeMetrics(failingWriter{httptest.NewRecorder()}, []*metric{m}))
+}
+
+// failingWriter is a response writer whose writes fail
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestExporterWriteError(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	e := newTestExporter(t, server)
+	e.logger = log.New(&logs, "", 0)
+	assert.Nil(t, e.poll(context.Background()))
+
+	e.ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "unable to write metrics: io: read/write on closed pipe\n", logs.String())
+}
+
+func TestRun(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	env := map[string]string{
+		"OCTOPUS_API_KEY":  server.APIKey,
+		"OCTOPUS_ACCOUNT":  "A-1234ABCD",
+		"OCTOPUS_BASE_URL": server.BaseURL(),
+	}
+	getenv := func(key string) string { return env[key] }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	done := make(chan int, 1)
+	var stderr bytes.Buffer
+	go func() {
+		done <- run(ctx, []string{"-listen", "127.0.0.1:0"}, &stderr, getenv, ready)
+	}()
+
+	select {
+	case addr := <-ready:
+		// Wait for the first poll
+		var body string
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			resp, err := http.Get("http://" + addr + "/metrics")
+			if !assert.Nil(t, err) {
+				break
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			body = string(data)
+			if strings.Contains(body, "octopus_exporter_polls_total 1") {
+				break
+			}
+		}
+		assert.Contains(t, body, "octopus_exporter_poll_errors_total 0")
+	case code := <-done:
+		t.Fatalf("exited with %d: %s", code, stderr.String())
+	}
+
+	cancel()
+	assert.Equal(t, exitOK, <-done)
+
+	// Missing account
+	delete(env, "OCTOPUS_ACCOUNT")
+	assert.Equal(t, exitUsage, run(context.Background(), nil, io.Discard, getenv, nil))
+}
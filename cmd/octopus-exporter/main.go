@@ -0,0 +1,151 @@
+// Command octopus-exporter exposes consumption and prices of an Octopus
+// Energy account as Prometheus metrics.
+//
+// Usage:
+//
+//	octopus-exporter [flags]
+//
+// Meters and tariffs are discovered from the account. The API is polled
+// periodically and scrapes of /metrics are served from the results of the
+// latest poll, so they never reach the API.
+//
+// The API key and account number are read from OCTOPUS_API_KEY and
+// OCTOPUS_ACCOUNT environment variables, OCTOPUS_BASE_URL optionally sets
+// the URL of the API.
+//
+// Exit status is 0 after a shutdown, 1 if the server fails and 2 on invalid
+// usage.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// cacheTTLs caches the account, which changes rarely, and tariff charges
+// for the half-hour period they are requested for. Consumption is not cached.
+var cacheTTLs = map[string]time.Duration{
+	"accounts/":                       time.Hour,
+	"products/*/electricity-tariffs/": ratePeriod,
+	"products/*/gas-tariffs/":         ratePeriod,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stderr, os.Getenv, nil)
+	stop()
+
+	os.Exit(code)
+}
+
+// run runs the exporter until ctx is cancelled and returns exit status.
+// If ready is not nil, the address of the listener is sent to it once the
+// server is listening.
+func run(ctx context.Context, args []string, stderr io.Writer, getenv func(string) string, ready chan<- string) int {
+	fs := flag.NewFlagSet("octopus-exporter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", ":9742", "address to listen on")
+	interval := fs.Duration("interval", 15*time.Minute, "interval of polling the API")
+	since := fs.Duration("since", 7*24*time.Hour, "collect consumption starting this long before the start")
+	account := fs.String("account", getenv("OCTOPUS_ACCOUNT"), "account number, defaults to OCTOPUS_ACCOUNT")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	logger := log.New(stderr, "octopus-exporter: ", log.LstdFlags)
+
+	apiKey := getenv("OCTOPUS_API_KEY")
+	switch {
+	case apiKey == "":
+		logger.Print("API key is not set, use OCTOPUS_API_KEY")
+		return exitUsage
+	case *account == "":
+		logger.Print("account number is not set, use -account or OCTOPUS_ACCOUNT")
+		return exitUsage
+	case *interval <= 0:
+		logger.Print("interval should be positive")
+		return exitUsage
+	}
+
+	health := &transport{next: http.DefaultTransport}
+	options := []octopusenergyapi.Option{
+		octopusenergyapi.WithUserAgent("octopus-exporter"),
+		octopusenergyapi.WithLogger(logger),
+		octopusenergyapi.WithCache(octopusenergyapi.NewMemoryCache(100), cacheTTLs),
+	}
+	if baseURL := getenv("OCTOPUS_BASE_URL"); baseURL != "" {
+		options = append(options, octopusenergyapi.WithBaseURL(baseURL))
+	}
+
+	client, err := octopusenergyapi.NewClient(apiKey, &http.Client{Transport: health, Timeout: time.Minute}, options...)
+	if err != nil {
+		logger.Print(err)
+		return exitUsage
+	}
+
+	e := newExporter(client, *account, time.Now().Add(-*since), health, logger)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><a href="/metrics">Metrics</a></body></html>`)
+	})
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logger.Print(err)
+		return exitError
+	}
+	if ready != nil {
+		ready <- ln.Addr().String()
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go e.run(ctx, *interval)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		logger.Print(err)
+		return exitError
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Print(err)
+		return exitError
+	}
+
+	return exitOK
+}
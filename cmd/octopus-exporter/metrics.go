@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentType is the content type of the Prometheus text format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metric is a metric family in the Prometheus text format
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+// sample is a single value of a metric
+type sample struct {
+	// suffix is appended to the metric name, e.g. _sum of a summary
+	suffix string
+	// labels are pairs of label names and values
+	labels []string
+	value  float64
+}
+
+// add adds a sample with labels given as name, value pairs
+func (m *metric) add(value float64, labels ...string) {
+	m.samples = append(m.samples, sample{labels: labels, value: value})
+}
+
+// writeMetrics writes metrics in the Prometheus text format. Metrics without
+// samples are left out.
+func writeMetrics(w io.Writer, metrics []*metric) error {
+	ew := &errWriter{w: bufio.NewWriter(w)}
+
+	for _, m := range metrics {
+		if len(m.samples) == 0 {
+			continue
+		}
+
+		ew.write("# HELP " + m.name + " " + escapeHelp(m.help) + "\n")
+		ew.write("# TYPE " + m.name + " " + m.kind + "\n")
+
+		for _, s := range m.samples {
+			ew.write(m.name + s.suffix)
+			if len(s.labels) > 0 {
+				ew.write("{")
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						ew.write(",")
+					}
+					ew.write(s.labels[i] + `="` + escapeLabel(s.labels[i+1]) + `"`)
+				}
+				ew.write("}")
+			}
+			ew.write(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+
+		if ew.err != nil {
+			return ew.err
+		}
+	}
+
+	return ew.w.Flush()
+}
+
+// errWriter writes strings until a write fails, keeping the first error
+type errWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (ew *errWriter) write(s string) {
+	if ew.err == nil {
+		_, ew.err = ew.w.WriteString(s)
+	}
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// transport is an http.RoundTripper counting requests made by the client
+type transport struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	requests int
+	errors   int
+	duration time.Duration
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.requests++
+	t.duration += elapsed
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		t.errors++
+	}
+
+	return resp, err
+}
+
+// metrics returns health metrics of the client
+func (t *transport) metrics() []*metric {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests := &metric{name: "octopus_api_requests_total", help: "Requests made to the Octopus Energy API.", kind: "counter"}
+	requests.add(float64(t.requests))
+
+	errors := &metric{name: "octopus_api_request_errors_total", help: "Requests which failed or returned an error status.", kind: "counter"}
+	errors.add(float64(t.errors))
+
+	duration := &metric{name: "octopus_api_request_duration_seconds", help: "Latency of requests to the Octopus Energy API.", kind: "summary"}
+	duration.samples = []sample{
+		{suffix: "_sum", value: t.duration.Seconds()},
+		{suffix: "_count", value: float64(t.requests)},
+	}
+
+	return []*metric{requests, errors, duration}
+}
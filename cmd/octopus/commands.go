@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/compare"
+	"github.com/pkg/errors"
+)
+
+// app holds state of a command being run
+type app struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	// Set by common flags
+	format     string
+	configPath string
+
+	cfg config
+}
+
+// flags returns a flag set of a command, with common flags defined
+func (a *app) flags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: octopus %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&a.format, "o", formatTable, "output format: table, json or csv")
+	fs.StringVar(&a.configPath, "config", "", "path of the config file")
+
+	return fs
+}
+
+// parse parses flags and checks the number of positional arguments is
+// between minArgs and maxArgs
+func (a *app) parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errFlags
+	}
+
+	switch a.format {
+	case formatTable, formatJSON, formatCSV:
+	default:
+		return usagef("invalid output format %s", a.format)
+	}
+
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		fs.Usage()
+		return errFlags
+	}
+
+	return nil
+}
+
+// client loads the config and returns a client
+func (a *app) client() (*octopusenergyapi.Client, error) {
+	cfg, err := loadConfig(a.configPath, a.getenv)
+	if err != nil {
+		return nil, err
+	}
+	a.cfg = cfg
+
+	if cfg.APIKey == "" {
+		return nil, usagef("API key is not set, use OCTOPUS_API_KEY or the config file")
+	}
+
+	options := []octopusenergyapi.Option{
+		octopusenergyapi.WithUserAgent("octopus-cli"),
+	}
+	if cfg.BaseURL != "" {
+		options = append(options, octopusenergyapi.WithBaseURL(cfg.BaseURL))
+	}
+
+	return octopusenergyapi.NewClient(cfg.APIKey, nil, options...)
+}
+
+// optionalBool is a boolean flag, which is nil unless set
+type optionalBool struct {
+	v *bool
+}
+
+func (b *optionalBool) String() string {
+	if b.v == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(*b.v)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	b.v = &v
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// timeFlag is a time flag, accepting RFC 3339 or a date in local time
+type timeFlag struct {
+	t time.Time
+}
+
+func (f *timeFlag) String() string {
+	return formatTime(f.t)
+}
+
+func (f *timeFlag) Set(s string) error {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		f.t = t
+		return nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return errors.Errorf("invalid time %s, use YYYY-MM-DD or RFC 3339", s)
+	}
+
+	f.t = t
+	return nil
+}
+
+func runProducts(a *app, args []string) error {
+	if len(args) == 0 {
+		return usagef("missing subcommand, use products list or products get")
+	}
+
+	switch args[0] {
+	case "list":
+		return runProductsList(a, args[1:])
+	case "get":
+		return runProductsGet(a, args[1:])
+	}
+
+	return usagef("unknown subcommand products %s", args[0])
+}
+
+func runProductsList(a *app, args []string) error {
+	fs := a.flags("products list", "products list [flags]")
+	var isVariable, isGreen, isTracker, isPrepay, isBusiness optionalBool
+	fs.Var(&isVariable, "variable", "only variable (or -variable=false fixed) products")
+	fs.Var(&isGreen, "green", "only green products")
+	fs.Var(&isTracker, "tracker", "only tracker products")
+	fs.Var(&isPrepay, "prepay", "only prepay products")
+	fs.Var(&isBusiness, "business", "only business products")
+	var availableAt timeFlag
+	fs.Var(&availableAt, "available-at", "list products available at a time instead of now")
+	brand := fs.String("brand", "", "only products of a brand, e.g. OCTOPUS_ENERGY")
+	if err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	products, err := client.ListProductsWithOptionsContext(a.ctx, octopusenergyapi.ListProductsOption{
+		IsVariable:  isVariable.v,
+		IsGreen:     isGreen.v,
+		IsTracker:   isTracker.v,
+		IsPrepay:    isPrepay.v,
+		IsBusiness:  isBusiness.v,
+		AvailableAt: availableAt.t,
+		Brand:       *brand,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"CODE", "NAME", "BRAND", "VARIABLE", "GREEN", "TRACKER", "AVAILABLE FROM", "AVAILABLE TO"}}
+	for _, p := range products {
+		t.add(p.Code, p.DisplayName, p.Brand, strconv.FormatBool(p.IsVariable), strconv.FormatBool(p.IsGreen),
+			strconv.FormatBool(p.IsTracker), formatTime(p.AvailableFrom), formatTime(p.AvailableTo))
+	}
+
+	return a.write(products, t)
+}
+
+func runProductsGet(a *app, args []string) error {
+	fs := a.flags("products get", "products get [flags] PRODUCT")
+	var at timeFlag
+	fs.Var(&at, "at", "show tariffs active at a time instead of now")
+	if err := a.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	product, err := client.GetProductWithOptionsContext(a.ctx, fs.Arg(0), octopusenergyapi.ProductOption{TariffsActiveAt: at.t})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"TYPE", "REGION", "PAYMENT", "TARIFF", "STANDING CHARGE", "UNIT RATE", "NIGHT UNIT RATE"}}
+	for _, region := range sortedKeys(product.SingleRegisterElecTariffs) {
+		for _, payment := range sortedKeys(product.SingleRegisterElecTariffs[region]) {
+			tariff := product.SingleRegisterElecTariffs[region][payment]
+			t.add("electricity", region, payment, tariff.Code, formatFloat(tariff.StandingChargeIncVAT),
+				formatFloat(tariff.StandardUnitRateIncVAT), "")
+		}
+	}
+	for _, region := range sortedKeys(product.DualRegisterElecTariffs) {
+		for _, payment := range sortedKeys(product.DualRegisterElecTariffs[region]) {
+			tariff := product.DualRegisterElecTariffs[region][payment]
+			t.add("electricity-dual", region, payment, tariff.Code, formatFloat(tariff.StandingChargeIncVAT),
+				formatFloat(tariff.DayUnitRateIncVAT), formatFloat(tariff.NightUnitRateIncVAT))
+		}
+	}
+	for _, region := range sortedKeys(product.SingleRegisterGasTariffs) {
+		for _, payment := range sortedKeys(product.SingleRegisterGasTariffs[region]) {
+			tariff := product.SingleRegisterGasTariffs[region][payment]
+			t.add("gas", region, payment, tariff.Code, formatFloat(tariff.StandingChargeIncVAT),
+				formatFloat(tariff.StandardUnitRateIncVAT), "")
+		}
+	}
+
+	return a.write(product, t)
+}
+
+func runMeterPoint(a *app, args []string) error {
+	fs := a.flags("meterpoint", "meterpoint [flags] MPAN|MPRN")
+	gas := fs.Bool("gas", false, "show a gas meter point, identified by MPRN")
+	if err := a.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	if *gas {
+		mp, err := client.GetGasMeterPointContext(a.ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		return a.write(mp, table{
+			header: []string{"MPRN", "CONSUMPTION STANDARD", "METERS"},
+			rows:   [][]string{{mp.MPRN, strconv.Itoa(mp.ConsumptionStandard), serialNumbers(mp.Meters)}},
+		})
+	}
+
+	mp, err := client.GetMeterPointContext(a.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return a.write(mp, table{
+		header: []string{"MPAN", "GSP", "REGION", "PROFILE CLASS"},
+		rows:   [][]string{{mp.MPAN, mp.GSP.GSPGroupID, mp.GSP.Name, strconv.Itoa(mp.ProfileClass)}},
+	})
+}
+
+func runGSP(a *app, args []string) error {
+	fs := a.flags("gsp", "gsp [flags] POSTCODE")
+	if err := a.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	gsp, err := client.GetGridSupplyPointContext(a.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return a.write(gsp, table{
+		header: []string{"GSP", "REGION", "OPERATOR", "PHONE NUMBER"},
+		rows:   [][]string{{gsp.GSPGroupID, gsp.Name, gsp.Operator, gsp.PhoneNumber}},
+	})
+}
+
+func runConsumption(a *app, args []string) error {
+	fs := a.flags("consumption", "consumption [flags] MPAN|MPRN SERIAL")
+	gas := fs.Bool("gas", false, "show consumption of a gas meter, identified by MPRN")
+	from := timeFlag{time.Now().AddDate(0, 0, -7)}
+	fs.Var(&from, "from", "start of the period (default 7 days ago)")
+	var to timeFlag
+	fs.Var(&to, "to", "end of the period")
+	groupBy := fs.String("group-by", "", "aggregate consumption by hour, day, week, month or quarter")
+	if err := a.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	options := octopusenergyapi.ConsumptionOption{
+		From:     from.t,
+		To:       to.t,
+		PageSize: 25000,
+		OrderBy:  "period",
+		GroupBy:  *groupBy,
+	}
+
+	var consumption []octopusenergyapi.Consumption
+	if *gas {
+		consumption, err = client.GetGasMeterConsumptionContext(a.ctx, fs.Arg(0), fs.Arg(1), options)
+	} else {
+		consumption, err = client.GetElecMeterConsumptionContext(a.ctx, fs.Arg(0), fs.Arg(1), options)
+	}
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"START", "END", "CONSUMPTION"}}
+	for _, c := range consumption {
+		t.add(formatTime(c.IntervalStart), formatTime(c.IntervalEnd), formatFloat(c.Value))
+	}
+
+	return a.write(consumption, t)
+}
+
+func runRates(a *app, args []string) error {
+	fs := a.flags("rates", "rates [flags] TARIFF")
+	charge := fs.String("charge", "standard", "charge to show: standard, day or night unit rates, or standing")
+	from := timeFlag{startOfDay(time.Now())}
+	fs.Var(&from, "from", "start of the period (default today)")
+	var to timeFlag
+	fs.Var(&to, "to", "end of the period")
+	if err := a.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	// Product and fuel are determined from the tariff code, e.g. E-1R-AGILE-18-02-21-C
+	tariffCode := fs.Arg(0)
+	productCode := octopusenergyapi.Agreement{TariffCode: tariffCode}.ProductCode()
+	if productCode == "" {
+		return usagef("invalid tariff code %s", tariffCode)
+	}
+	gas := strings.HasPrefix(tariffCode, "G-")
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	options := octopusenergyapi.RateOption{From: from.t, To: to.t, PageSize: 1500}
+
+	var rates []octopusenergyapi.Rate
+	switch {
+	case *charge == "standard" && gas:
+		rates, err = client.GetGasStandardUnitRatesContext(a.ctx, productCode, tariffCode, options)
+	case *charge == "standard":
+		rates, err = client.GetElecStandardUnitRatesContext(a.ctx, productCode, tariffCode, options)
+	case *charge == "day" && !gas:
+		rates, err = client.GetElecDayUnitRatesContext(a.ctx, productCode, tariffCode, options)
+	case *charge == "night" && !gas:
+		rates, err = client.GetElecNightUnitRatesContext(a.ctx, productCode, tariffCode, options)
+	case *charge == "standing" && gas:
+		rates, err = client.GetGasStandingChargesContext(a.ctx, productCode, tariffCode, options)
+	case *charge == "standing":
+		rates, err = client.GetElecStandingChargesContext(a.ctx, productCode, tariffCode, options)
+	default:
+		return usagef("invalid charge %s for tariff %s", *charge, tariffCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"VALID FROM", "VALID TO", "EXC VAT", "INC VAT", "PAYMENT METHOD"}}
+	for _, r := range rates {
+		t.add(formatTime(r.ValidFrom), formatTime(r.ValidTo), formatFloat(r.ValueExcVAT), formatFloat(r.ValueIncVAT), r.PaymentMethod)
+	}
+
+	return a.write(rates, t)
+}
+
+func runAccount(a *app, args []string) error {
+	fs := a.flags("account", "account [flags] [NUMBER]")
+	if err := a.parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	number := fs.Arg(0)
+	if number == "" {
+		number = a.cfg.Account
+	}
+	if number == "" {
+		return usagef("account number is not set, pass it as an argument or use OCTOPUS_ACCOUNT")
+	}
+
+	account, err := client.GetAccountContext(a.ctx, number)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"POSTCODE", "FUEL", "METER POINT", "SERIAL NUMBERS", "TARIFF", "VALID FROM", "VALID TO"}}
+	for _, p := range account.Properties {
+		for _, mp := range p.ElecMeterPoints {
+			serials := serialNumbers(mp.Meters)
+			for _, agreement := range mp.Agreements {
+				t.add(p.Postcode, "electricity", mp.MPAN, serials, agreement.TariffCode,
+					formatTime(agreement.ValidFrom), formatTime(agreement.ValidTo))
+			}
+		}
+
+		for _, mp := range p.GasMeterPoints {
+			serials := serialNumbers(mp.Meters)
+			for _, agreement := range mp.Agreements {
+				t.add(p.Postcode, "gas", mp.MPRN, serials, agreement.TariffCode,
+					formatTime(agreement.ValidFrom), formatTime(agreement.ValidTo))
+			}
+		}
+	}
+
+	return a.write(account, t)
+}
+
+func runCompare(a *app, args []string) error {
+	fs := a.flags("compare", "compare [flags] MPAN SERIAL")
+	gasMPRN := fs.String("gas-mprn", "", "include consumption of a gas meter point")
+	gasSerial := fs.String("gas-serial", "", "serial number of the gas meter")
+	gasUnit := fs.String("gas-unit", "", "unit of gas consumption, kWh for SMETS1 or m3 for SMETS2 meters (required with -gas-mprn)")
+	calorificValue := fs.Float64("calorific-value", defaultCalorificValue, "calorific value of gas in MJ/m3, converts m3 to kWh")
+	from := timeFlag{startOfDay(time.Now()).AddDate(-1, 0, 0)}
+	fs.Var(&from, "from", "start of the compared period (default a year ago)")
+	var to timeFlag
+	fs.Var(&to, "to", "end of the compared period")
+	payment := fs.String("payment", compare.DirectDebitMonthly, "payment method of tariffs")
+	if err := a.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	if (*gasMPRN == "") != (*gasSerial == "") {
+		return usagef("-gas-mprn and -gas-serial must be used together")
+	}
+	if *gasMPRN != "" {
+		switch *gasUnit {
+		case gasUnitKWh, gasUnitM3:
+		case "":
+			return usagef("-gas-unit is required with -gas-mprn, kWh for SMETS1 or m3 for SMETS2 meters")
+		default:
+			return usagef("invalid gas unit %s, should be kWh or m3", *gasUnit)
+		}
+		if !(*calorificValue > 0) {
+			return usagef("invalid calorific value %g", *calorificValue)
+		}
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	mp, err := client.GetMeterPointContext(a.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	options := octopusenergyapi.ConsumptionOption{From: from.t, To: to.t, PageSize: 25000}
+	household := compare.Household{GSP: mp.GSP}
+
+	household.Electricity, err = client.GetElecMeterConsumptionContext(a.ctx, fs.Arg(0), fs.Arg(1), options)
+	if err != nil {
+		return err
+	}
+
+	if *gasMPRN != "" {
+		household.Gas, err = client.GetGasMeterConsumptionContext(a.ctx, *gasMPRN, *gasSerial, options)
+		if err != nil {
+			return err
+		}
+		if *gasUnit == gasUnitM3 {
+			household.Gas = gasKWh(household.Gas, *calorificValue)
+		}
+	}
+
+	results, err := compare.Compare(a.ctx, client, household, compare.Options{PaymentMethod: *payment, Location: time.Local})
+	if err != nil {
+		return err
+	}
+
+	// Products which failed to be compared are reported, but not written
+	compared := make([]compare.Result, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(a.stderr, "octopus: %v\n", r.Err)
+			continue
+		}
+		compared = append(compared, r)
+	}
+
+	t := table{header: []string{"PRODUCT", "NAME", "ANNUAL COST", "UNIT COST", "STANDING CHARGE", "EXIT FEES", "ESTIMATED"}}
+	for _, r := range compared {
+		t.add(r.Product.Code, r.Product.DisplayName, formatPounds(r.AnnualCost().IncVAT),
+			formatPounds(r.Annual.UnitCost.IncVAT), formatPounds(r.Annual.StandingCharge.IncVAT),
+			formatPounds(r.ExitFees.IncVAT), strconv.FormatBool(r.Estimated))
+	}
+
+	return a.write(compared, t)
+}
+
+// Units of gas consumption. SMETS2 gas meters report consumption in m3,
+// which is converted to kWh like on bills.
+const (
+	gasUnitKWh = "kWh"
+	gasUnitM3  = "m3"
+
+	// defaultCalorificValue is a typical calorific value of gas in MJ/m3
+	defaultCalorificValue = 39.5
+	// gasVolumeCorrection corrects gas volume for temperature and pressure
+	gasVolumeCorrection = 1.02264
+)
+
+// gasKWh converts gas consumption from m3 to kWh
+func gasKWh(consumption []octopusenergyapi.Consumption, calorificValue float64) []octopusenergyapi.Consumption {
+	converted := make([]octopusenergyapi.Consumption, len(consumption))
+	for i, c := range consumption {
+		c.Value = float32(float64(c.Value) * gasVolumeCorrection * calorificValue / 3.6)
+		converted[i] = c
+	}
+
+	return converted
+}
+
+func serialNumbers(meters []octopusenergyapi.Meter) string {
+	serials := make([]string, 0, len(meters))
+	for _, m := range meters {
+		serials = append(serials, m.SerialNumber)
+	}
+
+	return strings.Join(serials, " ")
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// config holds settings read from the config file and environment.
+//
+// The config file is JSON, e.g.:
+//
+//	{"api_key": "sk_live_...", "account": "A-1234ABCD"}
+//
+// It is read from -config flag, OCTOPUS_CONFIG or octopus/config.json in the
+// user's config directory (e.g. ~/.config on Linux). Environment variables
+// OCTOPUS_API_KEY, OCTOPUS_ACCOUNT and OCTOPUS_BASE_URL override the file.
+type config struct {
+	APIKey  string `json:"api_key"`
+	Account string `json:"account"`
+	BaseURL string `json:"base_url"`
+}
+
+// loadConfig reads config from path, or from the default location if empty
+func loadConfig(path string, getenv func(string) string) (config, error) {
+	var cfg config
+
+	if path == "" {
+		path = getenv("OCTOPUS_CONFIG")
+	}
+
+	// Default config file is optional
+	optional := false
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "octopus", "config.json")
+			optional = true
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err != nil && optional && errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return config{}, errors.Wrap(err, "unable to read config")
+		default:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return config{}, errors.Wrapf(err, "unable to parse config %s", path)
+			}
+		}
+	}
+
+	if v := getenv("OCTOPUS_API_KEY"); v != "" {
+		cfg.APIKey = v
+	}
+	if v := getenv("OCTOPUS_ACCOUNT"); v != "" {
+		cfg.Account = v
+	}
+	if v := getenv("OCTOPUS_BASE_URL"); v != "" {
+		cfg.BaseURL = v
+	}
+
+	return cfg, nil
+}
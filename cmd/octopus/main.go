@@ -0,0 +1,139 @@
+// Command octopus is a command-line client for the Octopus Energy API.
+//
+// Usage:
+//
+//	octopus <command> [flags] [arguments]
+//
+// The API key is read from OCTOPUS_API_KEY environment variable or from
+// a JSON config file, {"api_key": "..."}, given by -config flag, OCTOPUS_CONFIG
+// or octopus/config.json in the user's config directory. Output is printed
+// as a table, JSON or CSV, selected with -o flag of every command.
+//
+// Exit status is 0 on success, 1 if a request fails and 2 on invalid usage.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// usageError is returned on invalid command-line usage
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, v ...interface{}) error {
+	return usageError{fmt.Sprintf(format, v...)}
+}
+
+// errFlags is returned when flags are invalid, after flag package reported the error
+var errFlags = usageError{"invalid flags"}
+
+// command is a subcommand of octopus
+type command struct {
+	usage   string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"products":    {"products list|get [flags]", "list products or show a product with its tariffs", runProducts},
+	"meterpoint":  {"meterpoint [flags] MPAN|MPRN", "show an electricity or gas meter point", runMeterPoint},
+	"gsp":         {"gsp [flags] POSTCODE", "show the grid supply point of a postcode", runGSP},
+	"consumption": {"consumption [flags] MPAN|MPRN SERIAL", "show meter consumption", runConsumption},
+	"rates":       {"rates [flags] TARIFF", "show unit rates or standing charges of a tariff", runRates},
+	"account":     {"account [flags] [NUMBER]", "show properties, meters and agreements of an account", runAccount},
+	"compare":     {"compare [flags] MPAN SERIAL", "compare the cost of past consumption on available products", runCompare},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv)
+	stop()
+
+	os.Exit(code)
+}
+
+// run executes the command line and returns exit status
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "octopus: unknown command %s\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	a := &app{
+		ctx:    ctx,
+		stdout: stdout,
+		stderr: stderr,
+		getenv: getenv,
+	}
+
+	err := cmd.run(a, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case err == errFlags:
+		return exitUsage
+	}
+
+	fmt.Fprintf(stderr, "octopus: %v\n", err)
+
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	return exitError
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: octopus <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-40s %s\n", commands[name].usage, commands[name].summary)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run octopus <command> -h for flags of a command.")
+	fmt.Fprintln(w, "The API key is read from OCTOPUS_API_KEY or from the config file.")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/octopustest"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTest runs the command against a fake server, returning exit status and output
+func runTest(t *testing.T, env map[string]string, args ...string) (int, string, string) {
+	server := octopustest.NewServer()
+	t.Cleanup(server.Close)
+
+	// Empty config, so that config of the user running tests is ignored
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	getenv := func(key string) string {
+		if v, ok := env[key]; ok {
+			return v
+		}
+
+		switch key {
+		case "OCTOPUS_API_KEY":
+			return octopustest.APIKey
+		case "OCTOPUS_BASE_URL":
+			return server.BaseURL()
+		case "OCTOPUS_CONFIG":
+			return configPath
+		}
+
+		return ""
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr, getenv)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, stderr := runTest(t, nil)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "Usage: octopus")
+
+	code, stdout, _ := runTest(t, nil, "help")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "consumption")
+
+	code, _, stderr = runTest(t, nil, "unknown")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown command unknown")
+
+	code, _, _ = runTest(t, nil, "gsp", "-x", "SW1A 1AA")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runTest(t, nil, "gsp")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, stderr = runTest(t, nil, "gsp", "-o", "xml", "SW1A 1AA")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid output format")
+
+	code, _, stderr = runTest(t, map[string]string{"OCTOPUS_API_KEY": ""}, "gsp", "SW1A 1AA")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "API key is not set")
+}
+
+func TestRunErrors(t *testing.T) {
+	code, _, stderr := runTest(t, nil, "meterpoint", "9999999999")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "404")
+
+	code, _, _ = runTest(t, map[string]string{"OCTOPUS_API_KEY": "wrongkey"}, "products", "get", "AGILE-18-02-21")
+	assert.Equal(t, exitError, code)
+}
+
+func TestRunConfig(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(config{APIKey: octopustest.APIKey, BaseURL: server.BaseURL(), Account: "A-1234ABCD"})
+	assert.Nil(t, os.WriteFile(path, data, 0o600))
+
+	var stdout bytes.Buffer
+	code := run(context.Background(), []string{"account", "-config", path}, &stdout, &bytes.Buffer{}, func(string) string { return "" })
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), "E-1R-AGILE-18-02-21-C")
+}
+
+func TestRunProducts(t *testing.T) {
+	code, stdout, _ := runTest(t, nil, "products", "list", "-green")
+	assert.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[0], "CODE"))
+		assert.True(t, strings.HasPrefix(lines[1], "AGILE-18-02-21"))
+	}
+
+	code, stdout, _ = runTest(t, nil, "products", "get", "-o", "json", "FIX-12M-20-09-21")
+	assert.Equal(t, exitOK, code)
+	var product octopusenergyapi.Product
+	if assert.Nil(t, json.Unmarshal([]byte(stdout), &product)) {
+		assert.Equal(t, "FIX-12M-20-09-21", product.Code)
+	}
+
+	code, _, _ = runTest(t, nil, "products", "delete")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestRunMeterPoint(t *testing.T) {
+	code, stdout, _ := runTest(t, nil, "meterpoint", "-o", "csv", "0123456789012")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "MPAN,GSP,REGION,PROFILE CLASS\n0123456789012,_C,London,1\n", stdout)
+
+	code, stdout, _ = runTest(t, nil, "meterpoint", "-o", "csv", "-gas", "1234567890")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "MPRN,CONSUMPTION STANDARD,METERS\n1234567890,12000,G4A01234567890\n", stdout)
+
+	code, stdout, _ = runTest(t, nil, "gsp", "SW1A 1AA")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "London")
+}
+
+func TestRunConsumption(t *testing.T) {
+	code, stdout, stderr := runTest(t, nil, "consumption", "-o", "csv", "-group-by", "day",
+		"-from", "2020-11-27T00:00:00Z", "-to", "2020-11-29T00:00:00Z", "0123456789012", "19L0123456")
+	assert.Equal(t, exitOK, code, stderr)
+
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if assert.Nil(t, err) && assert.Len(t, records, 3) {
+		assert.Equal(t, []string{"START", "END", "CONSUMPTION"}, records[0])
+		assert.Equal(t, "2020-11-27T00:00:00Z", records[1][0])
+		assert.Equal(t, "2020-11-28T00:00:00Z", records[2][0])
+	}
+}
+
+func TestRunRates(t *testing.T) {
+	code, stdout, stderr := runTest(t, nil, "rates", "-o", "json",
+		"-from", "2020-11-27T16:00:00Z", "-to", "2020-11-27T19:00:00Z", "E-1R-AGILE-18-02-21-C")
+	assert.Equal(t, exitOK, code, stderr)
+	var rates []octopusenergyapi.Rate
+	if assert.Nil(t, json.Unmarshal([]byte(stdout), &rates)) {
+		assert.Len(t, rates, 6)
+	}
+
+	code, stdout, _ = runTest(t, nil, "rates", "-charge", "standing", "G-1R-FIX-12M-20-09-21-C")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "17.85")
+
+	code, _, _ = runTest(t, nil, "rates", "-charge", "night", "G-1R-FIX-12M-20-09-21-C")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runTest(t, nil, "rates", "AGILE")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestRunCompare(t *testing.T) {
+	code, stdout, stderr := runTest(t, nil, "compare", "-o", "csv",
+		"-from", "2020-11-26T00:00:00Z", "-to", "2020-11-29T00:00:00Z", "0123456789012", "19L0123456")
+	assert.Equal(t, exitOK, code, stderr)
+
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if assert.Nil(t, err) && assert.Len(t, records, 3) {
+		assert.Equal(t, "ANNUAL COST", records[0][2])
+		assert.Equal(t, "AGILE-18-02-21", records[1][0])
+		assert.Equal(t, "FIX-12M-20-09-21", records[2][0])
+		assert.Equal(t, "0.25", records[2][5])
+	}
+
+	// Agile has no gas tariff
+	gasCost := func(unit string) string {
+		code, stdout, stderr := runTest(t, nil, "compare", "-o", "csv", "-gas-mprn", "1234567890", "-gas-serial", "G4A01234567890", "-gas-unit", unit,
+			"-from", "2020-11-26T00:00:00Z", "-to", "2020-11-29T00:00:00Z", "0123456789012", "19L0123456")
+		assert.Equal(t, exitOK, code, stderr)
+		assert.NotContains(t, stdout, "AGILE")
+
+		records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+		if assert.Nil(t, err) && assert.Len(t, records, 2) {
+			assert.Equal(t, "FIX-12M-20-09-21", records[1][0])
+			return records[1][3]
+		}
+		return ""
+	}
+
+	// Gas consumption in m3 is converted to kWh, which costs more
+	kWhCost, err := strconv.ParseFloat(gasCost("kWh"), 64)
+	assert.Nil(t, err)
+	m3Cost, err := strconv.ParseFloat(gasCost("m3"), 64)
+	assert.Nil(t, err)
+	assert.Greater(t, m3Cost, kWhCost)
+
+	for _, args := range [][]string{
+		{"-gas-mprn", "1234567890"},
+		{"-gas-mprn", "1234567890", "-gas-serial", "G4A01234567890"},
+		{"-gas-mprn", "1234567890", "-gas-serial", "G4A01234567890", "-gas-unit", "ft3"},
+		{"-gas-mprn", "1234567890", "-gas-serial", "G4A01234567890", "-gas-unit", "m3", "-calorific-value", "0"},
+	} {
+		code, _, _ = runTest(t, nil, append(append([]string{"compare"}, args...), "0123456789012", "19L0123456")...)
+		assert.Equal(t, exitUsage, code, args)
+	}
+}
@@ -0,0 +1,317 @@
+// Package compare simulates the cost of historical consumption on available
+// products, to show whether switching tariff is worth it.
+//
+// Each product is billed with the billing package, using the price history
+// of its tariff for the household's region over the consumption period.
+package compare
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/billing"
+	"github.com/pkg/errors"
+)
+
+// DirectDebitMonthly is the default payment method of tariffs
+const DirectDebitMonthly = "direct_debit_monthly"
+
+// daysPerYear is used to scale costs of the compared period to a year
+const daysPerYear = 365
+
+// Household is the usage compared on products
+type Household struct {
+	// GSP determines the region of tariffs
+	GSP octopusenergyapi.GridSupplyPoint
+	// Electricity is consumption of a single-register electricity meter in kWh
+	Electricity []octopusenergyapi.Consumption
+	// Gas is gas consumption in kWh. Products without a gas tariff are not
+	// compared when gas consumption is present.
+	Gas []octopusenergyapi.Consumption
+}
+
+// Options represents optional parameters for Compare
+type Options struct {
+	// Products filters compared products, all currently available products
+	// are compared by default
+	Products octopusenergyapi.ListProductsOption
+	// PaymentMethod selects tariffs by payment method key, such as
+	// direct_debit_monthly (default) or direct_debit_quarterly
+	PaymentMethod string
+	// Location determines boundaries of billed days, defaults to UTC
+	Location *time.Location
+}
+
+// Result is the cost of the household's usage on a product
+type Result struct {
+	Product        octopusenergyapi.Product
+	ElecTariffCode string
+	GasTariffCode  string
+	// Period is the total cost of the compared period, for all fuels
+	Period billing.Total
+	// Annual is the cost of Period scaled to a year
+	Annual billing.Total
+	// ExitFees are fees for leaving the product before the end of its term
+	ExitFees billing.Amount
+	// Estimated is set when the price history of a tariff does not cover
+	// the whole period, and its current prices were used for the rest
+	Estimated bool
+	// Err is set if the product could not be compared, costs are then zero
+	Err error
+}
+
+// AnnualCost returns the annual cost, including standing charges
+func (r Result) AnnualCost() billing.Amount {
+	return r.Annual.Cost()
+}
+
+// Compare calculates the cost of the household's consumption on products
+// available in its region and returns results ordered from the cheapest.
+// Products without a tariff for the region, payment method or metered fuels
+// are left out, as are dual-register electricity tariffs. Products which
+// fail to be compared are returned last, with Err set.
+func Compare(ctx context.Context, api octopusenergyapi.API, household Household, options Options) ([]Result, error) {
+	if household.GSP.GSPGroupID == "" {
+		return nil, errors.New("grid supply point is not set")
+	}
+	if len(household.Electricity) == 0 && len(household.Gas) == 0 {
+		return nil, errors.New("no consumption to compare")
+	}
+
+	paymentMethod := options.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = DirectDebitMonthly
+	}
+
+	products, err := api.ListProductsWithOptionsContext(ctx, options.Products)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list products")
+	}
+
+	c := comparer{
+		api:           api,
+		household:     household,
+		region:        household.GSP.GSPGroupID,
+		paymentMethod: paymentMethod,
+		billing: billing.Options{
+			Location:      options.Location,
+			PaymentMethod: ratePaymentMethod(paymentMethod),
+		},
+	}
+
+	var results []Result
+	for _, p := range products {
+		product, err := api.GetProductContext(ctx, p.Code)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrapf(err, "unable to retrieve product %s", p.Code)
+			}
+			results = append(results, Result{Product: p, Err: errors.Wrapf(err, "unable to retrieve product %s", p.Code)})
+			continue
+		}
+
+		result, ok, err := c.compare(ctx, product)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrapf(err, "unable to compare product %s", p.Code)
+			}
+			results = append(results, Result{Product: product, Err: errors.Wrapf(err, "unable to compare product %s", p.Code)})
+			continue
+		}
+		if ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].AnnualCost().IncVAT < results[j].AnnualCost().IncVAT
+	})
+
+	return results, nil
+}
+
+// comparer holds state shared by comparisons of products
+type comparer struct {
+	api           octopusenergyapi.API
+	household     Household
+	region        string
+	paymentMethod string
+	billing       billing.Options
+}
+
+// tariffFunc retrieves the price history of a tariff
+type tariffFunc func(ctx context.Context, api octopusenergyapi.API, productCode, tariffCode string, from, to time.Time) (billing.Tariff, error)
+
+// compare calculates the cost of a product. It returns false if the product
+// is not available to the household.
+func (c *comparer) compare(ctx context.Context, product octopusenergyapi.Product) (Result, bool, error) {
+	result := Result{Product: product}
+
+	fuels := []struct {
+		consumption []octopusenergyapi.Consumption
+		tariffs     map[string]map[string]octopusenergyapi.Tariff
+		get         tariffFunc
+		code        *string
+	}{
+		{c.household.Electricity, product.SingleRegisterElecTariffs, billing.ElecTariff, &result.ElecTariffCode},
+		{c.household.Gas, product.SingleRegisterGasTariffs, billing.GasTariff, &result.GasTariffCode},
+	}
+
+	for _, fuel := range fuels {
+		if len(fuel.consumption) == 0 {
+			continue
+		}
+
+		tariff, ok := fuel.tariffs[c.region][c.paymentMethod]
+		if !ok {
+			return Result{}, false, nil
+		}
+		*fuel.code = tariff.Code
+
+		total, estimated, err := c.cost(ctx, product.Code, tariff, fuel.consumption, fuel.get)
+		if errors.Is(err, octopusenergyapi.ErrNotFound) {
+			// Tariff has no published prices
+			return Result{}, false, nil
+		}
+		if err != nil {
+			return Result{}, false, errors.Wrapf(err, "tariff %s", tariff.Code)
+		}
+
+		result.Period = addTotal(result.Period, total)
+		result.Annual = addTotal(result.Annual, annualise(total))
+		result.ExitFees = result.ExitFees.Add(billing.Amount{
+			ExcVAT: float64(tariff.ExitFeesExcVAT),
+			IncVAT: float64(tariff.ExitFeesIncVAT),
+		})
+		result.Estimated = result.Estimated || estimated
+	}
+
+	return result, true, nil
+}
+
+// cost bills consumption on a tariff. Parts of the period before the price
+// history of the tariff starts are billed at its current prices.
+func (c *comparer) cost(ctx context.Context, productCode string, tariff octopusenergyapi.Tariff, consumption []octopusenergyapi.Consumption, get tariffFunc) (billing.Total, bool, error) {
+	from, to := period(consumption)
+
+	// Standing charges are billed from the start of the first billed day
+	from = startOfDay(from, c.billing.Location)
+
+	history, err := get(ctx, c.api, productCode, tariff.Code, from, to)
+	if err != nil {
+		return billing.Total{}, false, err
+	}
+
+	var filledRates, filledCharges bool
+	history.UnitRates, filledRates = fill(history.UnitRates, from, tariff.StandardUnitRateExcVAT, tariff.StandardUnitRateIncVAT)
+	history.StandingCharges, filledCharges = fill(history.StandingCharges, from, tariff.StandingChargeExcVAT, tariff.StandingChargeIncVAT)
+
+	bill, err := billing.Calculate(consumption, history, c.billing)
+	if err != nil {
+		return billing.Total{}, false, err
+	}
+
+	return bill.Total, filledRates || filledCharges, nil
+}
+
+// period returns the start of the earliest and the end of the latest interval
+func period(consumption []octopusenergyapi.Consumption) (from, to time.Time) {
+	for _, c := range consumption {
+		if from.IsZero() || c.IntervalStart.Before(from) {
+			from = c.IntervalStart
+		}
+		if c.IntervalEnd.After(to) {
+			to = c.IntervalEnd
+		}
+	}
+
+	return from, to
+}
+
+// startOfDay returns the start of the day of t in loc, or in UTC if nil
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// fill adds a rate at the current price, valid from the start of the period
+// until the earliest rate. It returns false if rates already cover the start.
+func fill(rates []octopusenergyapi.Rate, from time.Time, excVAT, incVAT float32) ([]octopusenergyapi.Rate, bool) {
+	var earliest time.Time
+	for _, r := range rates {
+		if earliest.IsZero() || r.ValidFrom.Before(earliest) {
+			earliest = r.ValidFrom
+		}
+	}
+
+	if len(rates) > 0 && !earliest.After(from) {
+		return rates, false
+	}
+
+	return append(rates, octopusenergyapi.Rate{
+		ValueExcVAT: excVAT,
+		ValueIncVAT: incVAT,
+		ValidFrom:   from,
+		ValidTo:     earliest,
+	}), true
+}
+
+// annualise scales a total to a year
+func annualise(total billing.Total) billing.Total {
+	days := total.End.Sub(total.Start).Hours() / 24
+	if days <= 0 {
+		return billing.Total{}
+	}
+	factor := daysPerYear / days
+
+	return billing.Total{
+		Start:          total.Start,
+		End:            total.Start.AddDate(0, 0, daysPerYear),
+		Consumption:    total.Consumption * factor,
+		UnitCost:       scale(total.UnitCost, factor),
+		StandingCharge: scale(total.StandingCharge, factor),
+	}
+}
+
+func scale(a billing.Amount, factor float64) billing.Amount {
+	return billing.Amount{ExcVAT: a.ExcVAT * factor, IncVAT: a.IncVAT * factor}
+}
+
+// addTotal returns the sum of totals of different fuels, covering both periods
+func addTotal(a, b billing.Total) billing.Total {
+	sum := billing.Total{
+		Start:          a.Start,
+		End:            a.End,
+		Consumption:    a.Consumption + b.Consumption,
+		UnitCost:       a.UnitCost.Add(b.UnitCost),
+		StandingCharge: a.StandingCharge.Add(b.StandingCharge),
+	}
+	if sum.Start.IsZero() || b.Start.Before(sum.Start) {
+		sum.Start = b.Start
+	}
+	if b.End.After(sum.End) {
+		sum.End = b.End
+	}
+
+	return sum
+}
+
+// ratePaymentMethod converts a tariff payment method key, such as
+// direct_debit_monthly, to the payment method of rates, such as DIRECT_DEBIT
+func ratePaymentMethod(key string) string {
+	if strings.HasPrefix(key, "direct_debit") {
+		return "DIRECT_DEBIT"
+	}
+
+	return "NON_DIRECT_DEBIT"
+}
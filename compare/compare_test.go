@@ -0,0 +1,252 @@
+package compare
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/FileGo/octopusenergyapi/internal/fixture"
+	"github.com/FileGo/octopusenergyapi/octopustest"
+	"github.com/stretchr/testify/assert"
+)
+
+func gsp(t *testing.T, id string) octopusenergyapi.GridSupplyPoint {
+	for _, g := range octopusenergyapi.GSPs {
+		if g.GSPGroupID == id {
+			return g
+		}
+	}
+
+	t.Fatalf("unknown grid supply point %s", id)
+	return octopusenergyapi.GridSupplyPoint{}
+}
+
+func TestCompareElectricity(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	client, err := server.APIClient()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	household := Household{
+		GSP:         gsp(t, "_C"),
+		Electricity: fixture.HalfHourly(fixture.Day(2020, 11, 26), fixture.Day(2020, 11, 29), 1),
+	}
+
+	results, err := Compare(context.Background(), client, household, Options{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// Variable tariff in region C has no prices
+	if assert.Len(t, results, 2) {
+		agile, fixed := results[0], results[1]
+		assert.Equal(t, "AGILE-18-02-21", agile.Product.Code)
+		assert.Equal(t, "E-1R-AGILE-18-02-21-C", agile.ElecTariffCode)
+		assert.Empty(t, agile.GasTariffCode)
+		assert.False(t, agile.Estimated)
+		assert.InDelta(t, 1568.7, agile.Period.UnitCost.IncVAT, 1e-3)
+		assert.InDelta(t, 63, agile.Period.StandingCharge.IncVAT, 1e-3)
+		assert.InDelta(t, 144, agile.Period.Consumption, 1e-9)
+
+		assert.Equal(t, "FIX-12M-20-09-21", fixed.Product.Code)
+		assert.InDelta(t, (144*14.7+3*22.05)*365/3, fixed.AnnualCost().IncVAT, 1e-2)
+		assert.InDelta(t, 22.05*365, fixed.Annual.StandingCharge.IncVAT, 1e-2)
+		assert.InDelta(t, 25, fixed.ExitFees.IncVAT, 1e-9)
+	}
+}
+
+func TestCompareDualFuel(t *testing.T) {
+	server := octopustest.NewServer()
+	defer server.Close()
+
+	client, err := server.APIClient()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	household := Household{
+		GSP:         gsp(t, "_A"),
+		Electricity: fixture.HalfHourly(fixture.Day(2020, 11, 26), fixture.Day(2020, 11, 29), 1),
+		Gas:         fixture.HalfHourly(fixture.Day(2020, 11, 26), fixture.Day(2020, 11, 29), 1),
+	}
+
+	results, err := Compare(context.Background(), client, household, Options{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// Agile has no gas tariff
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "FIX-12M-20-09-21", results[0].Product.Code)
+		assert.Equal(t, "G-1R-FIX-12M-20-09-21-A", results[0].GasTariffCode)
+		assert.InDelta(t, 50, results[0].ExitFees.IncVAT, 1e-9)
+
+		variable := results[1]
+		assert.Equal(t, "VAR-17-01-11", variable.Product.Code)
+		assert.InDelta(t, 144*16.8+144*2.73, variable.Period.UnitCost.IncVAT, 1e-2)
+		assert.InDelta(t, 3*21.672+3*17.85, variable.Period.StandingCharge.IncVAT, 1e-2)
+		assert.InDelta(t, 288, variable.Period.Consumption, 1e-9)
+		assert.Equal(t, fixture.Day(2020, 11, 26), variable.Period.Start)
+	}
+
+	// No tariffs for quarterly direct debit
+	results, err = Compare(context.Background(), client, household, Options{PaymentMethod: "direct_debit_quarterly"})
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+func TestCompareEstimated(t *testing.T) {
+	product := octopusenergyapi.Product{
+		Code: "NEW-24-01-01",
+		SingleRegisterElecTariffs: map[string]map[string]octopusenergyapi.Tariff{
+			"_A": {DirectDebitMonthly: {
+				Code:                   "E-1R-NEW-24-01-01-A",
+				StandardUnitRateExcVAT: 20,
+				StandardUnitRateIncVAT: 21,
+				StandingChargeExcVAT:   40,
+				StandingChargeIncVAT:   42,
+			}},
+		},
+	}
+
+	fake := &octopustest.Fake{
+		ListProductsFunc: func(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+			return []octopusenergyapi.Product{{Code: product.Code}}, nil
+		},
+		GetProductFunc: func(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+			return product, nil
+		},
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			// Price changed during the period
+			return []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: fixture.Day(2024, 1, 2)}}, nil
+		},
+		GetElecStandingChargesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return nil, nil
+		},
+	}
+
+	household := Household{
+		GSP:         gsp(t, "_A"),
+		Electricity: fixture.HalfHourly(fixture.Day(2024, 1, 1), fixture.Day(2024, 1, 3), 0.5),
+	}
+
+	results, err := Compare(context.Background(), fake, household, Options{})
+	if assert.Nil(t, err) && assert.Len(t, results, 1) {
+		assert.True(t, results[0].Estimated)
+		assert.InDelta(t, 24*21+24*10.5, results[0].Period.UnitCost.IncVAT, 1e-3)
+		assert.InDelta(t, 2*42, results[0].Period.StandingCharge.IncVAT, 1e-3)
+	}
+
+	_, err = Compare(context.Background(), fake, Household{GSP: gsp(t, "_A")}, Options{})
+	assert.NotNil(t, err)
+	_, err = Compare(context.Background(), fake, Household{Electricity: household.Electricity}, Options{})
+	assert.NotNil(t, err)
+}
+
+func TestCompareMidDay(t *testing.T) {
+	tariff := octopusenergyapi.Tariff{
+		Code:                   "E-1R-NEW-24-01-01-A",
+		StandardUnitRateExcVAT: 20,
+		StandardUnitRateIncVAT: 21,
+		StandingChargeExcVAT:   40,
+		StandingChargeIncVAT:   42,
+	}
+	fake := &octopustest.Fake{
+		ListProductsFunc: func(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+			return []octopusenergyapi.Product{{Code: "NEW-24-01-01"}}, nil
+		},
+		GetProductFunc: func(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+			return octopusenergyapi.Product{
+				Code: productCode,
+				SingleRegisterElecTariffs: map[string]map[string]octopusenergyapi.Tariff{
+					"_A": {DirectDebitMonthly: tariff},
+				},
+			}, nil
+		},
+		// Price history starts after the consumption
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: fixture.Day(2024, 1, 2)}}, nil
+		},
+		GetElecStandingChargesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return []octopusenergyapi.Rate{{ValueExcVAT: 40, ValueIncVAT: 42, ValidFrom: fixture.Day(2024, 1, 2).Add(12 * time.Hour)}}, nil
+		},
+	}
+
+	// Consumption starts at noon
+	household := Household{
+		GSP:         gsp(t, "_A"),
+		Electricity: fixture.HalfHourly(fixture.Day(2024, 1, 1).Add(12*time.Hour), fixture.Day(2024, 1, 3), 1),
+	}
+
+	results, err := Compare(context.Background(), fake, household, Options{})
+	if assert.Nil(t, err) && assert.Len(t, results, 1) {
+		assert.True(t, results[0].Estimated)
+		assert.InDelta(t, 24*21+48*10.5, results[0].Period.UnitCost.IncVAT, 1e-3)
+		assert.InDelta(t, 2*42, results[0].Period.StandingCharge.IncVAT, 1e-3)
+	}
+
+	// Days start before the consumption in other time zones
+	results, err = Compare(context.Background(), fake, household, Options{Location: time.FixedZone("UTC-5", -5*60*60)})
+	if assert.Nil(t, err) && assert.Len(t, results, 1) {
+		assert.InDelta(t, 24*21+48*10.5, results[0].Period.UnitCost.IncVAT, 1e-3)
+	}
+}
+
+func TestCompareProductError(t *testing.T) {
+	products := map[string]octopusenergyapi.Product{}
+	for _, code := range []string{"GAP-24-01-01", "OK-24-01-01", "MISSING-24-01-01"} {
+		products[code] = octopusenergyapi.Product{
+			Code: code,
+			SingleRegisterElecTariffs: map[string]map[string]octopusenergyapi.Tariff{
+				"_A": {DirectDebitMonthly: {Code: "E-1R-" + code + "-A"}},
+			},
+		}
+	}
+
+	fake := &octopustest.Fake{
+		ListProductsFunc: func(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+			return []octopusenergyapi.Product{{Code: "GAP-24-01-01"}, {Code: "OK-24-01-01"}, {Code: "MISSING-24-01-01"}}, nil
+		},
+		GetProductFunc: func(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+			if productCode == "MISSING-24-01-01" {
+				return octopusenergyapi.Product{}, octopusenergyapi.ErrUnauthorized
+			}
+			return products[productCode], nil
+		},
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			if productCode == "GAP-24-01-01" {
+				// Price history has a gap on the second day
+				return []octopusenergyapi.Rate{
+					{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: fixture.Day(2024, 1, 1), ValidTo: fixture.Day(2024, 1, 2)},
+					{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: fixture.Day(2024, 1, 2).Add(time.Hour)},
+				}, nil
+			}
+			return []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: fixture.Day(2024, 1, 1)}}, nil
+		},
+		GetElecStandingChargesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return []octopusenergyapi.Rate{{ValueExcVAT: 40, ValueIncVAT: 42, ValidFrom: fixture.Day(2024, 1, 1)}}, nil
+		},
+	}
+
+	household := Household{
+		GSP:         gsp(t, "_A"),
+		Electricity: fixture.HalfHourly(fixture.Day(2024, 1, 1), fixture.Day(2024, 1, 3), 1),
+	}
+
+	results, err := Compare(context.Background(), fake, household, Options{})
+	if assert.Nil(t, err) && assert.Len(t, results, 3) {
+		assert.Equal(t, "OK-24-01-01", results[0].Product.Code)
+		assert.Nil(t, results[0].Err)
+		assert.InDelta(t, 96*10.5, results[0].Period.UnitCost.IncVAT, 1e-3)
+
+		for _, r := range results[1:] {
+			if assert.NotNil(t, r.Err, r.Product.Code) {
+				assert.Contains(t, r.Err.Error(), r.Product.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package octopusenergyapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxErrorBodySize limits how much of an error response is read
+const maxErrorBodySize = 64 << 10
+
+var (
+	// ErrNotFound is matched by an APIError with 404 status code
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized is matched by an APIError with 401 or 403 status code
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNoGridSupplyPoint is returned when a grid supply point can't be determined
+	ErrNoGridSupplyPoint = errors.New("no grid supply point found")
+)
+
+// APIError represents an unsuccessful response received from the API.
+// It can be matched against ErrNotFound and ErrUnauthorized using errors.Is.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Detail is the error description sent by the server, if any
+	Detail string
+	// Path is the requested path, relative to the base URL
+	Path string
+	// RetryAfter is the delay requested by the server in Retry-After header
+	RetryAfter time.Duration
+}
+
+// newAPIError creates an APIError from an unsuccessful response
+func newAPIError(resp *http.Response, path string) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Path:       path,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	// Body is in the form of {"detail": "..."}, but it's not guaranteed
+	data := struct {
+		Detail string `json:"detail"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodySize)).Decode(&data); err == nil {
+		apiErr.Detail = data.Detail
+	}
+
+	return apiErr
+}
+
+// Error implements error interface
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("http error - code %d received: %s", e.StatusCode, e.Detail)
+	}
+
+	return fmt.Sprintf("http error - code %d received", e.StatusCode)
+}
+
+// Retryable reports whether repeating the request may succeed
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Is reports whether the error matches target
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+
+	return false
+}
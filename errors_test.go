@@ -0,0 +1,130 @@
+package octopusenergyapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		statusCode   int
+		notFound     bool
+		unauthorized bool
+		retryable    bool
+	}{
+		{http.StatusNotFound, true, false, false},
+		{http.StatusUnauthorized, false, true, false},
+		{http.StatusForbidden, false, true, false},
+		{http.StatusTooManyRequests, false, false, true},
+		{http.StatusInternalServerError, false, false, true},
+		{http.StatusBadGateway, false, false, true},
+		{http.StatusBadRequest, false, false, false},
+	}
+
+	for _, test := range tests {
+		var err error = &APIError{StatusCode: test.statusCode}
+		err = errors.Wrap(err, "error retrieving")
+
+		assert.Equal(t, test.notFound, errors.Is(err, ErrNotFound), test.statusCode)
+		assert.Equal(t, test.unauthorized, errors.Is(err, ErrUnauthorized), test.statusCode)
+
+		var apiErr *APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, test.retryable, apiErr.Retryable(), test.statusCode)
+		}
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	t.Run("detail", func(t *testing.T) {
+		f, err := os.Open("./testdata/notfound.json")
+		assert.Nil(t, err)
+		defer f.Close()
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, err = io.Copy(w, f)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetProduct("UNKNOWN")
+			if assert.NotNil(t, err) {
+				assert.True(t, errors.Is(err, ErrNotFound))
+				assert.Contains(t, err.Error(), "error retrieving the product")
+				assert.Contains(t, err.Error(), "Not found.")
+				assert.NotContains(t, err.Error(), "fakeapikey")
+
+				var apiErr *APIError
+				if assert.True(t, errors.As(err, &apiErr)) {
+					assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+					assert.Equal(t, "Not found.", apiErr.Detail)
+					assert.Equal(t, "products/UNKNOWN/", apiErr.Path)
+				}
+			}
+		}
+	})
+
+	t.Run("no_detail", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			err = client.do(context.Background(), "testpath", nil)
+			if assert.NotNil(t, err) {
+				assert.True(t, errors.Is(err, ErrUnauthorized))
+				assert.Equal(t, "http error - code 401 received", err.Error())
+			}
+		}
+	})
+}
+
+func TestErrNoGridSupplyPoint(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		fn   func(*Client) error
+	}{
+		{"meterpoint", "./testdata/getmeterpoint_nogsp.json", func(c *Client) error {
+			_, err := c.GetMeterPoint("0123456789")
+			return err
+		}},
+		{"gridsupplypoint", "./testdata/getgridsupplypoint_nogsp.json", func(c *Client) error {
+			_, err := c.GetGridSupplyPoint("SW1A 1AA")
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.Open(test.file)
+			assert.Nil(t, err)
+			defer f.Close()
+
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err = io.Copy(w, f)
+				assert.Nil(t, err)
+			})
+			httpClient, teardown := testingHTTPClient(h)
+			defer teardown()
+
+			client, err := NewClient("fakeapikey", httpClient)
+			if assert.Nil(t, err) {
+				assert.True(t, errors.Is(test.fn(client), ErrNoGridSupplyPoint))
+			}
+		})
+	}
+}
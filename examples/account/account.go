@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+)
+
+func main() {
+	// Account number, as shown in the online dashboard
+	accountNumber := "A-1234ABCD"
+
+	client, err := octopusenergyapi.NewClient("{API_KEY}", http.DefaultClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	account, err := client.GetAccount(accountNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, property := range account.Properties {
+		fmt.Printf("Property: %s, %s\n", property.AddressLine1, property.Postcode)
+
+		for _, mp := range property.ElecMeterPoints {
+			for _, meter := range mp.Meters {
+				fmt.Printf("Electricity MPAN: %s Serial number: %s\n", mp.MPAN, meter.SerialNumber)
+			}
+
+			for _, agreement := range mp.Agreements {
+				if agreement.Active(time.Now()) {
+					fmt.Printf("Current tariff: %s (product %s)\n", agreement.TariffCode, agreement.ProductCode())
+				}
+			}
+		}
+
+		for _, mp := range property.GasMeterPoints {
+			for _, meter := range mp.Meters {
+				fmt.Printf("Gas MPRN: %s Serial number: %s\n", mp.MPRN, meter.SerialNumber)
+			}
+		}
+	}
+}
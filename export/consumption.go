@@ -0,0 +1,100 @@
+package export
+
+import (
+	"io"
+
+	"github.com/FileGo/octopusenergyapi"
+)
+
+// Columns of consumption
+const (
+	ColumnIntervalStart Column = "interval_start"
+	ColumnIntervalEnd   Column = "interval_end"
+	ColumnConsumption   Column = "consumption"
+)
+
+// ConsumptionColumns are all columns of consumption, in default order
+var ConsumptionColumns = []Column{ColumnIntervalStart, ColumnIntervalEnd, ColumnConsumption}
+
+var consumptionFields = map[Column]field[octopusenergyapi.Consumption]{
+	ColumnIntervalStart: {
+		kind: kindTime,
+		get: func(c octopusenergyapi.Consumption, s settings) string {
+			return s.formatTime(c.IntervalStart)
+		},
+		set: func(c *octopusenergyapi.Consumption, value string, s settings) (err error) {
+			c.IntervalStart, err = s.parseTime(value)
+			return err
+		},
+	},
+	ColumnIntervalEnd: {
+		kind: kindTime,
+		get: func(c octopusenergyapi.Consumption, s settings) string {
+			return s.formatTime(c.IntervalEnd)
+		},
+		set: func(c *octopusenergyapi.Consumption, value string, s settings) (err error) {
+			c.IntervalEnd, err = s.parseTime(value)
+			return err
+		},
+	},
+	ColumnConsumption: {
+		kind: kindNumber,
+		get: func(c octopusenergyapi.Consumption, s settings) string {
+			return s.formatValue(c.Value)
+		},
+		set: func(c *octopusenergyapi.Consumption, value string, s settings) (err error) {
+			c.Value, err = s.parseValue(value)
+			return err
+		},
+	},
+}
+
+// WriteConsumptionCSV writes consumption as CSV
+func WriteConsumptionCSV(w io.Writer, consumption []octopusenergyapi.Consumption, options Options) error {
+	s, err := newSettings(options, consumptionUnits)
+	if err != nil {
+		return err
+	}
+
+	cols, fields, err := columns(consumptionFields, ConsumptionColumns, options.Columns)
+	if err != nil {
+		return err
+	}
+
+	return writeCSV(w, consumption, cols, fields, s, !options.NoHeader)
+}
+
+// WriteConsumptionJSONL writes consumption as JSON Lines, one object per interval
+func WriteConsumptionJSONL(w io.Writer, consumption []octopusenergyapi.Consumption, options Options) error {
+	s, err := newSettings(options, consumptionUnits)
+	if err != nil {
+		return err
+	}
+
+	cols, fields, err := columns(consumptionFields, ConsumptionColumns, options.Columns)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONL(w, consumption, cols, fields, s)
+}
+
+// ReadConsumptionCSV reads consumption written by WriteConsumptionCSV
+func ReadConsumptionCSV(r io.Reader, options Options) ([]octopusenergyapi.Consumption, error) {
+	s, err := newSettings(options, consumptionUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	return readCSV(r, consumptionFields, ConsumptionColumns, options, s)
+}
+
+// ReadConsumptionJSONL reads consumption written by WriteConsumptionJSONL
+func ReadConsumptionJSONL(r io.Reader, options Options) ([]octopusenergyapi.Consumption, error) {
+	s, err := newSettings(options, consumptionUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	return readJSONL(r, consumptionFields, s)
+}
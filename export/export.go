@@ -0,0 +1,320 @@
+// Package export writes and reads consumption and rate series as CSV and
+// JSON Lines, e.g. for loading into spreadsheets and notebooks.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Column identifies an exported field. Column names are used in CSV header
+// and as JSON keys.
+type Column string
+
+// Units of exported values. Consumption is converted from kWh, which is the
+// unit of electricity and SMETS1 gas meters; consumption of SMETS2 gas meters
+// is in m^3 and should be exported in UnitKWh, which leaves it unchanged.
+// Rates are converted from pence.
+const (
+	UnitKWh    = "kWh"
+	UnitWh     = "Wh"
+	UnitMWh    = "MWh"
+	UnitPence  = "p"
+	UnitPounds = "GBP"
+)
+
+var (
+	consumptionUnits = map[string]float64{"": 1, UnitKWh: 1, UnitWh: 1000, UnitMWh: 0.001}
+	rateUnits        = map[string]float64{"": 1, UnitPence: 1, UnitPounds: 0.01}
+)
+
+// Options represents optional parameters for writers and readers.
+// The same options should be used to read data as were used to write it.
+type Options struct {
+	// Location of exported times, defaults to UTC
+	Location *time.Location
+	// TimeFormat is the layout of exported times, defaults to time.RFC3339
+	TimeFormat string
+	// Columns are exported columns, in order. Defaults to all columns.
+	// When reading CSV with a header, columns are taken from the header.
+	Columns []Column
+	// Unit of values, e.g. UnitWh for consumption or UnitPounds for rates.
+	// Defaults to kWh for consumption and pence for rates.
+	Unit string
+	// NoHeader omits the header row of CSV
+	NoHeader bool
+}
+
+// fieldKind determines how a field is encoded in JSON
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindTime
+)
+
+// field describes how a column of T is formatted and parsed
+type field[T any] struct {
+	kind fieldKind
+	// get formats the field, empty values are exported as empty CSV fields
+	// and JSON nulls
+	get func(v T, s settings) string
+	// set parses a non-empty value
+	set func(v *T, value string, s settings) error
+}
+
+// settings are Options with defaults applied
+type settings struct {
+	location   *time.Location
+	timeFormat string
+	factor     float64
+}
+
+func newSettings(options Options, units map[string]float64) (settings, error) {
+	s := settings{
+		location:   options.Location,
+		timeFormat: options.TimeFormat,
+	}
+
+	if s.location == nil {
+		s.location = time.UTC
+	}
+	if s.timeFormat == "" {
+		s.timeFormat = time.RFC3339
+	}
+
+	factor, ok := units[options.Unit]
+	if !ok {
+		return settings{}, errors.Errorf("unsupported unit %s", options.Unit)
+	}
+	s.factor = factor
+
+	return s, nil
+}
+
+func (s settings) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.In(s.location).Format(s.timeFormat)
+}
+
+func (s settings) parseTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation(s.timeFormat, value, s.location)
+	return t, errors.Wrapf(err, "invalid time %s", value)
+}
+
+func (s settings) formatValue(v float32) string {
+	return strconv.FormatFloat(float64(float32(float64(v)*s.factor)), 'f', -1, 32)
+}
+
+func (s settings) parseValue(value string) (float32, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value %s", value)
+	}
+
+	return float32(f / s.factor), nil
+}
+
+// columns returns fields of selected columns
+func columns[T any](fields map[Column]field[T], all, selected []Column) ([]Column, []field[T], error) {
+	if len(selected) == 0 {
+		selected = all
+	}
+
+	selectedFields := make([]field[T], len(selected))
+	for i, column := range selected {
+		f, ok := fields[column]
+		if !ok {
+			return nil, nil, errors.Errorf("unknown column %s", column)
+		}
+		selectedFields[i] = f
+	}
+
+	return selected, selectedFields, nil
+}
+
+func writeCSV[T any](w io.Writer, items []T, cols []Column, fields []field[T], s settings, header bool) error {
+	cw := csv.NewWriter(w)
+
+	if header {
+		record := make([]string, len(cols))
+		for i, column := range cols {
+			record[i] = string(column)
+		}
+		if err := cw.Write(record); err != nil {
+			return errors.Wrap(err, "unable to write header")
+		}
+	}
+
+	record := make([]string, len(fields))
+	for _, item := range items {
+		for i, f := range fields {
+			record[i] = f.get(item, s)
+		}
+		if err := cw.Write(record); err != nil {
+			return errors.Wrap(err, "unable to write record")
+		}
+	}
+
+	cw.Flush()
+	return errors.Wrap(cw.Error(), "unable to write records")
+}
+
+func writeJSONL[T any](w io.Writer, items []T, cols []Column, fields []field[T], s settings) error {
+	bw := bufio.NewWriter(w)
+
+	// Keys are written in the order of columns
+	keys := make([]string, len(cols))
+	for i, column := range cols {
+		key, err := json.Marshal(string(column))
+		if err != nil {
+			return errors.Wrap(err, "unable to encode column")
+		}
+		keys[i] = string(key)
+	}
+
+	var line strings.Builder
+	for _, item := range items {
+		line.Reset()
+		line.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(keys[i])
+			line.WriteByte(':')
+
+			value := f.get(item, s)
+			switch {
+			case value == "":
+				line.WriteString("null")
+			case f.kind == kindNumber:
+				line.WriteString(value)
+			default:
+				quoted, err := json.Marshal(value)
+				if err != nil {
+					return errors.Wrap(err, "unable to encode value")
+				}
+				line.Write(quoted)
+			}
+		}
+		line.WriteString("}\n")
+
+		if _, err := bw.WriteString(line.String()); err != nil {
+			return errors.Wrap(err, "unable to write record")
+		}
+	}
+
+	return errors.Wrap(bw.Flush(), "unable to write records")
+}
+
+func readCSV[T any](r io.Reader, fields map[Column]field[T], all []Column, options Options, s settings) ([]T, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	cols := options.Columns
+	if !options.NoHeader {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read header")
+		}
+
+		cols = make([]Column, len(header))
+		for i, name := range header {
+			cols[i] = Column(strings.TrimSpace(name))
+		}
+	}
+
+	cols, selected, err := columns(fields, all, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read record")
+		}
+
+		if len(record) != len(selected) {
+			return nil, errors.Errorf("record %d has %d fields, expected %d", len(items)+1, len(record), len(selected))
+		}
+
+		var item T
+		for i, f := range selected {
+			if record[i] == "" {
+				continue
+			}
+			if err := f.set(&item, record[i], s); err != nil {
+				return nil, errors.Wrapf(err, "record %d, column %s", len(items)+1, cols[i])
+			}
+		}
+		items = append(items, item)
+	}
+}
+
+func readJSONL[T any](r io.Reader, fields map[Column]field[T], s settings) ([]T, error) {
+	scanner := bufio.NewScanner(r)
+
+	var items []T
+	for line := 1; scanner.Scan(); line++ {
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(data), &object); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+
+		var item T
+		for key, raw := range object {
+			f, ok := fields[Column(key)]
+			if !ok {
+				// Unknown keys are ignored, like in encoding/json
+				continue
+			}
+
+			var value string
+			switch {
+			case string(raw) == "null":
+				continue
+			case len(raw) > 0 && raw[0] == '"':
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, errors.Wrapf(err, "line %d, column %s", line, key)
+				}
+			default:
+				value = string(raw)
+			}
+
+			if value == "" {
+				continue
+			}
+			if err := f.set(&item, value, s); err != nil {
+				return nil, errors.Wrapf(err, "line %d, column %s", line, key)
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, errors.Wrap(scanner.Err(), "unable to read records")
+}
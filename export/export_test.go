@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/stretchr/testify/assert"
+)
+
+var testConsumption = []octopusenergyapi.Consumption{
+	{Value: 0.2, IntervalStart: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), IntervalEnd: time.Date(2020, 6, 1, 0, 30, 0, 0, time.UTC)},
+	{Value: 0.125, IntervalStart: time.Date(2020, 6, 1, 0, 30, 0, 0, time.UTC), IntervalEnd: time.Date(2020, 6, 1, 1, 0, 0, 0, time.UTC)},
+}
+
+var testRates = []octopusenergyapi.Rate{
+	{ValueExcVAT: 15.51, ValueIncVAT: 16.2855, ValidFrom: time.Date(2017, 1, 11, 0, 0, 0, 0, time.UTC), ValidTo: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC), PaymentMethod: "DIRECT_DEBIT"},
+	{ValueExcVAT: 16, ValueIncVAT: 16.8, ValidFrom: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+// equalConsumption compares consumption, ignoring locations of times
+func equalConsumption(t *testing.T, expected, actual []octopusenergyapi.Consumption) {
+	if assert.Len(t, actual, len(expected)) {
+		for i := range expected {
+			assert.InDelta(t, expected[i].Value, actual[i].Value, 1e-6)
+			assert.True(t, expected[i].IntervalStart.Equal(actual[i].IntervalStart))
+			assert.True(t, expected[i].IntervalEnd.Equal(actual[i].IntervalEnd))
+		}
+	}
+}
+
+func TestConsumptionCSV(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, WriteConsumptionCSV(&buf, testConsumption, Options{}))
+		assert.Equal(t, "interval_start,interval_end,consumption\n"+
+			"2020-06-01T00:00:00Z,2020-06-01T00:30:00Z,0.2\n"+
+			"2020-06-01T00:30:00Z,2020-06-01T01:00:00Z,0.125\n", buf.String())
+
+		consumption, err := ReadConsumptionCSV(&buf, Options{})
+		if assert.Nil(t, err) {
+			equalConsumption(t, testConsumption, consumption)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		london, err := time.LoadLocation("Europe/London")
+		if err != nil {
+			t.Skip("time zone database not available")
+		}
+
+		options := Options{
+			Location:   london,
+			TimeFormat: "2006-01-02 15:04",
+			Columns:    []Column{ColumnConsumption, ColumnIntervalStart},
+			Unit:       UnitWh,
+			NoHeader:   true,
+		}
+
+		var buf bytes.Buffer
+		assert.Nil(t, WriteConsumptionCSV(&buf, testConsumption, options))
+		assert.Equal(t, "200,2020-06-01 01:00\n125,2020-06-01 01:30\n", buf.String())
+
+		consumption, err := ReadConsumptionCSV(&buf, options)
+		if assert.Nil(t, err) && assert.Len(t, consumption, 2) {
+			assert.Equal(t, float32(0.2), consumption[0].Value)
+			assert.True(t, testConsumption[1].IntervalStart.Equal(consumption[1].IntervalStart))
+			assert.True(t, consumption[1].IntervalEnd.IsZero())
+		}
+	})
+
+	t.Run("header_columns", func(t *testing.T) {
+		consumption, err := ReadConsumptionCSV(strings.NewReader("consumption,interval_start\n1.5,2020-06-01T00:00:00Z\n"), Options{})
+		if assert.Nil(t, err) && assert.Len(t, consumption, 1) {
+			assert.Equal(t, float32(1.5), consumption[0].Value)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NotNil(t, WriteConsumptionCSV(&buf, testConsumption, Options{Unit: UnitPounds}))
+		assert.NotNil(t, WriteConsumptionCSV(&buf, testConsumption, Options{Columns: []Column{ColumnValidFrom}}))
+
+		_, err := ReadConsumptionCSV(strings.NewReader("unknown\n1\n"), Options{})
+		assert.NotNil(t, err)
+
+		_, err = ReadConsumptionCSV(strings.NewReader("consumption\nabc\n"), Options{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "record 1, column consumption")
+		}
+	})
+}
+
+func TestConsumptionJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteConsumptionJSONL(&buf, testConsumption, Options{Unit: UnitWh}))
+	assert.Equal(t, `{"interval_start":"2020-06-01T00:00:00Z","interval_end":"2020-06-01T00:30:00Z","consumption":200}`+"\n"+
+		`{"interval_start":"2020-06-01T00:30:00Z","interval_end":"2020-06-01T01:00:00Z","consumption":125}`+"\n", buf.String())
+
+	consumption, err := ReadConsumptionJSONL(&buf, Options{Unit: UnitWh})
+	if assert.Nil(t, err) {
+		equalConsumption(t, testConsumption, consumption)
+	}
+
+	_, err = ReadConsumptionJSONL(strings.NewReader("{\"consumption\":1}\n{"), Options{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "line 2")
+	}
+}
+
+func TestRates(t *testing.T) {
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, WriteRatesCSV(&buf, testRates, Options{Unit: UnitPounds}))
+		assert.Equal(t, "valid_from,valid_to,value_exc_vat,value_inc_vat,payment_method\n"+
+			"2017-01-11T00:00:00Z,2020-10-01T00:00:00Z,0.1551,0.162855,DIRECT_DEBIT\n"+
+			"2020-10-01T00:00:00Z,,0.16,0.168,\n", buf.String())
+
+		rates, err := ReadRatesCSV(&buf, Options{Unit: UnitPounds})
+		if assert.Nil(t, err) && assert.Len(t, rates, 2) {
+			assert.InDelta(t, 15.51, rates[0].ValueExcVAT, 1e-4)
+			assert.Equal(t, "DIRECT_DEBIT", rates[0].PaymentMethod)
+			assert.True(t, rates[1].ValidTo.IsZero())
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, WriteRatesJSONL(&buf, testRates, Options{}))
+		assert.Equal(t, `{"valid_from":"2017-01-11T00:00:00Z","valid_to":"2020-10-01T00:00:00Z","value_exc_vat":15.51,"value_inc_vat":16.2855,"payment_method":"DIRECT_DEBIT"}`+"\n"+
+			`{"valid_from":"2020-10-01T00:00:00Z","valid_to":null,"value_exc_vat":16,"value_inc_vat":16.8,"payment_method":null}`+"\n", buf.String())
+
+		rates, err := ReadRatesJSONL(&buf, Options{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, len(testRates), len(rates))
+			for i := range testRates {
+				assert.Equal(t, testRates[i].ValueExcVAT, rates[i].ValueExcVAT)
+				assert.Equal(t, testRates[i].PaymentMethod, rates[i].PaymentMethod)
+				assert.True(t, testRates[i].ValidFrom.Equal(rates[i].ValidFrom))
+				assert.True(t, testRates[i].ValidTo.Equal(rates[i].ValidTo))
+			}
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"io"
+
+	"github.com/FileGo/octopusenergyapi"
+)
+
+// Columns of rates
+const (
+	ColumnValidFrom     Column = "valid_from"
+	ColumnValidTo       Column = "valid_to"
+	ColumnValueExcVAT   Column = "value_exc_vat"
+	ColumnValueIncVAT   Column = "value_inc_vat"
+	ColumnPaymentMethod Column = "payment_method"
+)
+
+// RateColumns are all columns of rates, in default order
+var RateColumns = []Column{ColumnValidFrom, ColumnValidTo, ColumnValueExcVAT, ColumnValueIncVAT, ColumnPaymentMethod}
+
+var rateFields = map[Column]field[octopusenergyapi.Rate]{
+	ColumnValidFrom: {
+		kind: kindTime,
+		get: func(r octopusenergyapi.Rate, s settings) string {
+			return s.formatTime(r.ValidFrom)
+		},
+		set: func(r *octopusenergyapi.Rate, value string, s settings) (err error) {
+			r.ValidFrom, err = s.parseTime(value)
+			return err
+		},
+	},
+	ColumnValidTo: {
+		kind: kindTime,
+		get: func(r octopusenergyapi.Rate, s settings) string {
+			return s.formatTime(r.ValidTo)
+		},
+		set: func(r *octopusenergyapi.Rate, value string, s settings) (err error) {
+			r.ValidTo, err = s.parseTime(value)
+			return err
+		},
+	},
+	ColumnValueExcVAT: {
+		kind: kindNumber,
+		get: func(r octopusenergyapi.Rate, s settings) string {
+			return s.formatValue(r.ValueExcVAT)
+		},
+		set: func(r *octopusenergyapi.Rate, value string, s settings) (err error) {
+			r.ValueExcVAT, err = s.parseValue(value)
+			return err
+		},
+	},
+	ColumnValueIncVAT: {
+		kind: kindNumber,
+		get: func(r octopusenergyapi.Rate, s settings) string {
+			return s.formatValue(r.ValueIncVAT)
+		},
+		set: func(r *octopusenergyapi.Rate, value string, s settings) (err error) {
+			r.ValueIncVAT, err = s.parseValue(value)
+			return err
+		},
+	},
+	ColumnPaymentMethod: {
+		kind: kindString,
+		get: func(r octopusenergyapi.Rate, s settings) string {
+			return r.PaymentMethod
+		},
+		set: func(r *octopusenergyapi.Rate, value string, s settings) error {
+			r.PaymentMethod = value
+			return nil
+		},
+	},
+}
+
+// WriteRatesCSV writes unit rates or standing charges as CSV
+func WriteRatesCSV(w io.Writer, rates []octopusenergyapi.Rate, options Options) error {
+	s, err := newSettings(options, rateUnits)
+	if err != nil {
+		return err
+	}
+
+	cols, fields, err := columns(rateFields, RateColumns, options.Columns)
+	if err != nil {
+		return err
+	}
+
+	return writeCSV(w, rates, cols, fields, s, !options.NoHeader)
+}
+
+// WriteRatesJSONL writes unit rates or standing charges as JSON Lines,
+// one object per rate
+func WriteRatesJSONL(w io.Writer, rates []octopusenergyapi.Rate, options Options) error {
+	s, err := newSettings(options, rateUnits)
+	if err != nil {
+		return err
+	}
+
+	cols, fields, err := columns(rateFields, RateColumns, options.Columns)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONL(w, rates, cols, fields, s)
+}
+
+// ReadRatesCSV reads rates written by WriteRatesCSV
+func ReadRatesCSV(r io.Reader, options Options) ([]octopusenergyapi.Rate, error) {
+	s, err := newSettings(options, rateUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	return readCSV(r, rateFields, RateColumns, options, s)
+}
+
+// ReadRatesJSONL reads rates written by WriteRatesJSONL
+func ReadRatesJSONL(r io.Reader, options Options) ([]octopusenergyapi.Rate, error) {
+	s, err := newSettings(options, rateUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	return readJSONL(r, rateFields, s)
+}
@@ -0,0 +1,28 @@
+// Package fixture provides consumption and time fixtures shared by tests.
+package fixture
+
+import (
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+)
+
+// Date returns a time in UTC
+func Date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+// Day returns midnight of a day in UTC
+func Day(year int, month time.Month, day int) time.Time {
+	return Date(year, month, day, 0, 0)
+}
+
+// HalfHourly returns consumption of value in every half-hour between from and to
+func HalfHourly(from, to time.Time, value float32) []octopusenergyapi.Consumption {
+	var consumption []octopusenergyapi.Consumption
+	for t := from; t.Before(to); t = t.Add(30 * time.Minute) {
+		consumption = append(consumption, octopusenergyapi.Consumption{Value: value, IntervalStart: t, IntervalEnd: t.Add(30 * time.Minute)})
+	}
+
+	return consumption
+}
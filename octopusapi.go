@@ -1,56 +1,76 @@
 package octopusenergyapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-var postcodeRegex *regexp.Regexp
+var postcodeRegex, mprnRegex *regexp.Regexp
 
 func init() {
+	// Compile MPRN regexp
+	mprnRegex = regexp.MustCompile(`^[0-9]{6,10}$`)
+
 	// Compile postcode regexp
 	postcodeRegex = regexp.MustCompile(`^([Gg][Ii][Rr] 0[Aa]{2})|((([A-Za-z][0-9]{1,2})|(([A-Za-z][A-Ha-hJ-Yj-y][0-9]{1,2})|(([AZa-z][0-9][A-Za-z])|([A-Za-z][A-Ha-hJ-Yj-y][0-9]?[A-Za-z])))) [0-9][A-Za-z]{2})$`)
 }
 
-// NewClient returns a client
-func NewClient(APIkey string, httpClient *http.Client) (*Client, error) {
+// NewClient returns a client. If httpClient is nil, http.DefaultClient is used.
+func NewClient(APIkey string, httpClient *http.Client, options ...Option) (*Client, error) {
 	// Empty APIkey is not permitted
 	APIkey = strings.TrimSpace(APIkey)
 	if len(APIkey) == 0 {
 		return nil, errors.New("API key should not be empty")
 	}
 
-	// Add APIkey as username to base URL
-	baseURL, err := urlAddUsername(baseURL, APIkey)
-	if err != nil {
-		return nil, errors.Errorf("unable to add username to url: %v", err)
+	if httpClient == nil {
+		httpClient = http.DefaultClient
 	}
 
-	return &Client{
-		URL:        baseURL,
+	c := &Client{
+		apiKey:     APIkey,
+		baseURL:    defaultBaseURL,
 		httpClient: httpClient,
-	}, nil
+		retry:      DefaultRetryPolicy,
+	}
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, errors.Wrap(err, "invalid option")
+		}
+	}
+
+	return c, nil
 }
 
 // GetMeterPoint retrieves an electricity meter point for a given MPAN
 // https://developer.octopus.energy/docs/api/#electricity-meter-points
 func (c *Client) GetMeterPoint(mpan string) (MeterPoint, error) {
+	return c.GetMeterPointContext(context.Background(), mpan)
+}
+
+// GetMeterPointContext is like GetMeterPoint but uses ctx for cancellation and deadlines
+func (c *Client) GetMeterPointContext(ctx context.Context, mpan string) (MeterPoint, error) {
 	data := struct {
 		GspID        string `json:"gsp"`
 		MPAN         string `json:"mpan"`
 		ProfileClass int    `json:"profile_class"`
 	}{}
 
-	err := c.do(fmt.Sprintf("electricity-meter-points/%s/", mpan), &data)
+	err := c.do(ctx, fmt.Sprintf("electricity-meter-points/%s/", mpan), &data)
 	if err != nil {
-		return MeterPoint{}, errors.Errorf("error retrieving meterpoint: %v", err)
+		return MeterPoint{}, errors.Wrap(err, "error retrieving meterpoint")
 	}
 
 	// Mask JSON struct into MeterPoint
@@ -66,33 +86,61 @@ func (c *Client) GetMeterPoint(mpan string) (MeterPoint, error) {
 		}
 	}
 
-	return MeterPoint{}, errors.New("no grid supply point found")
+	return MeterPoint{}, ErrNoGridSupplyPoint
+}
+
+// GetGasMeterPoint retrieves a gas meter point for a given MPRN
+func (c *Client) GetGasMeterPoint(mprn string) (GasMeterPoint, error) {
+	return c.GetGasMeterPointContext(context.Background(), mprn)
+}
+
+// GetGasMeterPointContext is like GetGasMeterPoint but uses ctx for cancellation and deadlines
+func (c *Client) GetGasMeterPointContext(ctx context.Context, mprn string) (GasMeterPoint, error) {
+	// Check if MPRN is valid
+	if !CheckMPRN(mprn) {
+		return GasMeterPoint{}, errors.Errorf("invalid mprn %s", mprn)
+	}
+
+	data := struct {
+		MPRN                string  `json:"mprn"`
+		ConsumptionStandard int     `json:"consumption_standard"`
+		Meters              []Meter `json:"meters"`
+	}{}
+
+	err := c.do(ctx, fmt.Sprintf("gas-meter-points/%s/", mprn), &data)
+	if err != nil {
+		return GasMeterPoint{}, errors.Wrap(err, "error retrieving gas meterpoint")
+	}
+
+	return GasMeterPoint{
+		MPRN:                data.MPRN,
+		ConsumptionStandard: data.ConsumptionStandard,
+		Meters:              data.Meters,
+	}, nil
 }
 
 // GetGridSupplyPoint gets a grid supply point based on postcode
 // https://developer.octopus.energy/docs/api/#list-grid-supply-points
 func (c *Client) GetGridSupplyPoint(postcode string) (GridSupplyPoint, error) {
+	return c.GetGridSupplyPointContext(context.Background(), postcode)
+}
+
+// GetGridSupplyPointContext is like GetGridSupplyPoint but uses ctx for cancellation and deadlines
+func (c *Client) GetGridSupplyPointContext(ctx context.Context, postcode string) (GridSupplyPoint, error) {
 	// Check if postcode is valid
 	if !checkPostcode(postcode) {
 		return GridSupplyPoint{}, errors.Errorf("invalid postcode %s", postcode)
 	}
 
-	// Remove spaces from postcode
-	postcode = strings.ReplaceAll(postcode, " ", "")
-
-	// Struct for JSON unmarshalling
-	data := struct {
-		Count    int    `json:"count"`
-		Next     string `json:"next"`
-		Previous string `json:"previous"`
-		Results  []struct {
-			GroupID string `json:"group_id"`
-		} `json:"results"`
-	}{}
+	var data Page[gspJSON]
 
-	err := c.do(fmt.Sprintf("industry/grid-supply-points/?postcode=%s", postcode), &data)
+	err := c.do(ctx, gspURL(postcode), &data)
 	if err != nil {
-		return GridSupplyPoint{}, errors.Errorf("error retrieving grid supply point: %v", err)
+		return GridSupplyPoint{}, errors.Wrap(err, "error retrieving grid supply point")
+	}
+
+	if len(data.Results) == 0 {
+		return GridSupplyPoint{}, errors.Wrapf(ErrNoGridSupplyPoint, "postcode %s", postcode)
 	}
 
 	// Only return data if we are dealing with a single result
@@ -106,27 +154,62 @@ func (c *Client) GetGridSupplyPoint(postcode string) (GridSupplyPoint, error) {
 		}
 	}
 
-	return GridSupplyPoint{}, errors.New("unknown grid supply point")
+	return GridSupplyPoint{}, errors.Wrapf(ErrNoGridSupplyPoint, "unknown grid supply point %s", data.Results[0].GroupID)
 }
 
-// GetMeterConsumption retrieves meter consumption
-// https://developer.octopus.energy/docs/api/#consumption
-func (c *Client) getMeterConsumption(fuel, mpan, serialNo string, options ConsumptionOption) ([]Consumption, error) {
-	data := struct {
-		Count        int           `json:"count"`
-		NextPage     string        `json:"next"`
-		PreviousPage string        `json:"previous"`
-		Results      []Consumption `json:"results"`
-	}{}
+// gspURL builds a request url for grid supply points of a postcode,
+// with spaces removed
+func gspURL(postcode string) string {
+	return "industry/grid-supply-points/?postcode=" + url.QueryEscape(strings.ReplaceAll(postcode, " ", ""))
+}
 
-	apiURL, err := url.Parse(fmt.Sprintf("%s-meter-points/%s/meters/%s/consumption/", fuel, mpan, serialNo))
+// GridSupplyPointsSeq returns an iterator over grid supply points.
+// If postcode is empty, all grid supply points are returned.
+// https://developer.octopus.energy/docs/api/#list-grid-supply-points
+func (c *Client) GridSupplyPointsSeq(ctx context.Context, postcode string) func(yield func(GridSupplyPoint, error) bool) {
+	URL := "industry/grid-supply-points/"
+	if postcode != "" {
+		// Check if postcode is valid
+		if !checkPostcode(postcode) {
+			return seqError[GridSupplyPoint](errors.Errorf("invalid postcode %s", postcode))
+		}
+
+		URL = gspURL(postcode)
+	}
+
+	seq := paginate[gspJSON](ctx, c, URL, "error retrieving grid supply points")
+
+	return func(yield func(GridSupplyPoint, error) bool) {
+		seq(func(data gspJSON, err error) bool {
+			if err != nil {
+				return yield(GridSupplyPoint{}, err)
+			}
+
+			for _, gsp := range GSPs {
+				if gsp.GSPGroupID == data.GroupID {
+					return yield(gsp, nil)
+				}
+			}
+
+			return yield(GridSupplyPoint{}, errors.Wrapf(ErrNoGridSupplyPoint, "unknown grid supply point %s", data.GroupID))
+		})
+	}
+}
+
+// consumptionURL builds a request url for meter consumption
+func consumptionURL(fuel, mpan, serialNo string, options ConsumptionOption, page int) (string, error) {
+	// Escape the path segments, so that they cannot add a query to the url
+	apiURL, err := url.Parse(fmt.Sprintf("%s-meter-points/%s/meters/%s/consumption/", fuel, url.PathEscape(mpan), url.PathEscape(serialNo)))
 	if err != nil {
-		return nil, errors.Errorf("unable to parse request url: %v", err)
+		return "", errors.Wrap(err, "unable to parse request url")
 	}
 
 	// Add options to URL if they are provided
-	if options != (ConsumptionOption{}) {
-		q := apiURL.Query()
+	if options != (ConsumptionOption{}) || page > 1 {
+		q := url.Values{}
+		if page > 1 {
+			q.Add("page", strconv.Itoa(page))
+		}
 		if options.PageSize != 0 {
 			q.Add("page_size", strconv.Itoa(options.PageSize))
 		}
@@ -145,104 +228,655 @@ func (c *Client) getMeterConsumption(fuel, mpan, serialNo string, options Consum
 		apiURL.RawQuery = q.Encode()
 	}
 
-	err = c.do(apiURL.String(), &data)
+	return apiURL.String(), nil
+}
+
+// getMeterConsumptionPage retrieves a single page of meter consumption
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) getMeterConsumptionPage(ctx context.Context, fuel, mpan, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error) {
+	URL, err := consumptionURL(fuel, mpan, serialNo, options, page)
+	if err != nil {
+		return ConsumptionPage{}, err
+	}
+
+	var data ConsumptionPage
+
+	err = c.do(ctx, URL, &data)
 	if err != nil {
-		return nil, errors.Errorf("error retrieving meter consumption: %v", err)
+		return ConsumptionPage{}, errors.Wrap(err, "error retrieving meter consumption")
 	}
 
-	return data.Results, nil
+	return data, nil
 }
 
-// GetElecMeterConsumption retrieves electricity consumption
+// meterConsumptionSeq returns an iterator over meter consumption
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) meterConsumptionSeq(ctx context.Context, fuel, mpan, serialNo string, options ConsumptionOption) func(yield func(Consumption, error) bool) {
+	URL, err := consumptionURL(fuel, mpan, serialNo, options, 0)
+	if err != nil {
+		return seqError[Consumption](err)
+	}
+
+	return paginate[Consumption](ctx, c, URL, "error retrieving meter consumption")
+}
+
+// getMeterConsumption retrieves meter consumption, following all pages
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) getMeterConsumption(ctx context.Context, fuel, mpan, serialNo string, options ConsumptionOption) ([]Consumption, error) {
+	return collect(c.meterConsumptionSeq(ctx, fuel, mpan, serialNo, options))
+}
+
+// getMeterConsumptionAll retrieves meter consumption, following all pages,
+// with the total count reported by the API
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) getMeterConsumptionAll(ctx context.Context, fuel, mpan, serialNo string, options ConsumptionOption) (ConsumptionPage, error) {
+	URL, err := consumptionURL(fuel, mpan, serialNo, options, 0)
+	if err != nil {
+		return ConsumptionPage{}, err
+	}
+
+	return collectPages[Consumption](ctx, c, URL, "error retrieving meter consumption")
+}
+
+// GetElecMeterConsumption retrieves electricity consumption for the whole
+// requested period, following all pages. GetElecMeterConsumptionAll also
+// returns the total count reported by the API.
 // https://developer.octopus.energy/docs/api/#consumption
 func (c *Client) GetElecMeterConsumption(mpan, serialNo string, options ConsumptionOption) ([]Consumption, error) {
-	return c.getMeterConsumption(fuelElectricity, mpan, serialNo, options)
+	return c.GetElecMeterConsumptionContext(context.Background(), mpan, serialNo, options)
+}
+
+// GetElecMeterConsumptionContext is like GetElecMeterConsumption but uses ctx for cancellation and deadlines
+func (c *Client) GetElecMeterConsumptionContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption) ([]Consumption, error) {
+	return c.getMeterConsumption(ctx, fuelElectricity, mpan, serialNo, options)
 }
 
-// GetGasMeterConsumption retrieves electricity consumption
+// GetGasMeterConsumption retrieves gas consumption for the whole
+// requested period, following all pages. GetGasMeterConsumptionAll also
+// returns the total count reported by the API.
 // https://developer.octopus.energy/docs/api/#consumption
-func (c *Client) GetGasMeterConsumption(mpan, serialNo string, options ConsumptionOption) ([]Consumption, error) {
-	return c.getMeterConsumption(fuelGas, mpan, serialNo, options)
+func (c *Client) GetGasMeterConsumption(mprn, serialNo string, options ConsumptionOption) ([]Consumption, error) {
+	return c.GetGasMeterConsumptionContext(context.Background(), mprn, serialNo, options)
 }
 
-// checkPostcode checks if provided string is a valid UK postcode
-func checkPostcode(postcode string) bool {
-	return postcodeRegex.MatchString(postcode)
+// GetGasMeterConsumptionContext is like GetGasMeterConsumption but uses ctx for cancellation and deadlines
+func (c *Client) GetGasMeterConsumptionContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption) ([]Consumption, error) {
+	return c.getMeterConsumption(ctx, fuelGas, mprn, serialNo, options)
 }
 
-// listProductsPage retrieves products from a single page of JSON data
-func (c *Client) listProductsPage(URL string) ([]Product, string, error) {
-	var data productJSON
+// GetElecMeterConsumptionAll retrieves electricity consumption for the whole
+// requested period, following all pages, as a single page with all results
+// and the total count reported by the API
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) GetElecMeterConsumptionAll(mpan, serialNo string, options ConsumptionOption) (ConsumptionPage, error) {
+	return c.GetElecMeterConsumptionAllContext(context.Background(), mpan, serialNo, options)
+}
 
-	err := c.do(URL, &data)
-	if err != nil {
-		return nil, "", errors.Errorf("error retrieving: %v", err)
-	}
+// GetElecMeterConsumptionAllContext is like GetElecMeterConsumptionAll but uses ctx for cancellation and deadlines
+func (c *Client) GetElecMeterConsumptionAllContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption) (ConsumptionPage, error) {
+	return c.getMeterConsumptionAll(ctx, fuelElectricity, mpan, serialNo, options)
+}
+
+// GetGasMeterConsumptionAll retrieves gas consumption for the whole
+// requested period, following all pages, as a single page with all results
+// and the total count reported by the API
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) GetGasMeterConsumptionAll(mprn, serialNo string, options ConsumptionOption) (ConsumptionPage, error) {
+	return c.GetGasMeterConsumptionAllContext(context.Background(), mprn, serialNo, options)
+}
 
-	return data.Results, strings.TrimPrefix(data.Next, baseURL), nil
+// GetGasMeterConsumptionAllContext is like GetGasMeterConsumptionAll but uses ctx for cancellation and deadlines
+func (c *Client) GetGasMeterConsumptionAllContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption) (ConsumptionPage, error) {
+	return c.getMeterConsumptionAll(ctx, fuelGas, mprn, serialNo, options)
+}
+
+// ElecMeterConsumptionSeq returns an iterator over electricity consumption,
+// retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) ElecMeterConsumptionSeq(ctx context.Context, mpan, serialNo string, options ConsumptionOption) func(yield func(Consumption, error) bool) {
+	return c.meterConsumptionSeq(ctx, fuelElectricity, mpan, serialNo, options)
+}
+
+// GasMeterConsumptionSeq returns an iterator over gas consumption,
+// retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) GasMeterConsumptionSeq(ctx context.Context, mprn, serialNo string, options ConsumptionOption) func(yield func(Consumption, error) bool) {
+	return c.meterConsumptionSeq(ctx, fuelGas, mprn, serialNo, options)
+}
+
+// GetElecMeterConsumptionPage retrieves a single page of electricity consumption.
+// Pages are numbered from 1.
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) GetElecMeterConsumptionPage(mpan, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error) {
+	return c.GetElecMeterConsumptionPageContext(context.Background(), mpan, serialNo, options, page)
+}
+
+// GetElecMeterConsumptionPageContext is like GetElecMeterConsumptionPage but uses ctx for cancellation and deadlines
+func (c *Client) GetElecMeterConsumptionPageContext(ctx context.Context, mpan, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error) {
+	return c.getMeterConsumptionPage(ctx, fuelElectricity, mpan, serialNo, options, page)
+}
+
+// GetGasMeterConsumptionPage retrieves a single page of gas consumption.
+// Pages are numbered from 1.
+// https://developer.octopus.energy/docs/api/#consumption
+func (c *Client) GetGasMeterConsumptionPage(mprn, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error) {
+	return c.GetGasMeterConsumptionPageContext(context.Background(), mprn, serialNo, options, page)
+}
+
+// GetGasMeterConsumptionPageContext is like GetGasMeterConsumptionPage but uses ctx for cancellation and deadlines
+func (c *Client) GetGasMeterConsumptionPageContext(ctx context.Context, mprn, serialNo string, options ConsumptionOption, page int) (ConsumptionPage, error) {
+	return c.getMeterConsumptionPage(ctx, fuelGas, mprn, serialNo, options, page)
+}
+
+// Bool returns a pointer to b, for use in options
+func Bool(b bool) *bool {
+	return &b
+}
+
+// CheckMPRN checks if provided string is a valid Meter Point Reference Number (MPRN)
+// of a gas meter point, i.e. 6 to 10 digits
+func CheckMPRN(mprn string) bool {
+	return mprnRegex.MatchString(mprn)
+}
+
+// checkPostcode checks if provided string is a valid UK postcode
+func checkPostcode(postcode string) bool {
+	return postcodeRegex.MatchString(postcode)
 }
 
 // ListProducts returns a list of energy products
 // https://developer.octopus.energy/docs/api/#list-products
 func (c *Client) ListProducts() ([]Product, error) {
-	var products []Product
+	return c.ListProductsWithOptionsContext(context.Background(), ListProductsOption{})
+}
 
-	URL := "/products/"
+// ListProductsContext is like ListProducts but uses ctx for cancellation and deadlines
+func (c *Client) ListProductsContext(ctx context.Context) ([]Product, error) {
+	return c.ListProductsWithOptionsContext(ctx, ListProductsOption{})
+}
 
-	for {
-		pageProducts, url, err := c.listProductsPage(URL)
-		URL = url
-		if err != nil {
-			return nil, errors.Errorf("error retrieving products page: %v", err)
-		}
+// ListProductsWithOptions returns a list of energy products matching options
+// https://developer.octopus.energy/docs/api/#list-products
+func (c *Client) ListProductsWithOptions(options ListProductsOption) ([]Product, error) {
+	return c.ListProductsWithOptionsContext(context.Background(), options)
+}
+
+// ListProductsWithOptionsContext is like ListProductsWithOptions but uses ctx for cancellation and deadlines
+func (c *Client) ListProductsWithOptionsContext(ctx context.Context, options ListProductsOption) ([]Product, error) {
+	return collect(c.ProductsSeq(ctx, options))
+}
+
+// ProductsSeq returns an iterator over energy products, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-products
+func (c *Client) ProductsSeq(ctx context.Context, options ListProductsOption) func(yield func(Product, error) bool) {
+	apiURL, err := url.Parse("products/")
+	if err != nil {
+		return seqError[Product](errors.Wrap(err, "unable to parse request url"))
+	}
 
-		products = append(products, pageProducts...)
-		if URL == "" {
-			break
+	// Add options to URL if they are provided
+	if options != (ListProductsOption{}) {
+		q := apiURL.Query()
+		for name, value := range map[string]*bool{
+			"is_variable": options.IsVariable,
+			"is_green":    options.IsGreen,
+			"is_tracker":  options.IsTracker,
+			"is_prepay":   options.IsPrepay,
+			"is_business": options.IsBusiness,
+		} {
+			if value != nil {
+				q.Add(name, strconv.FormatBool(*value))
+			}
 		}
+		if !options.AvailableAt.IsZero() {
+			q.Add("available_at", options.AvailableAt.Format(iso8601))
+		}
+		if options.Brand != "" {
+			q.Add("brand", options.Brand)
+		}
+		apiURL.RawQuery = q.Encode()
 	}
 
-	return products, nil
+	return paginate[Product](ctx, c, apiURL.String(), "error retrieving products")
 }
 
-// GetProduct retrieves a product based on its name
+// GetProduct retrieves a product based on its name, with tariffs active now
 // https://developer.octopus.energy/docs/api/#retrieve-a-product
 func (c *Client) GetProduct(productCode string) (Product, error) {
+	return c.GetProductWithOptionsContext(context.Background(), productCode, ProductOption{})
+}
+
+// GetProductContext is like GetProduct but uses ctx for cancellation and deadlines
+func (c *Client) GetProductContext(ctx context.Context, productCode string) (Product, error) {
+	return c.GetProductWithOptionsContext(ctx, productCode, ProductOption{})
+}
+
+// GetProductWithOptions retrieves a product based on its name, with tariffs
+// active at the time given in options, or now
+// https://developer.octopus.energy/docs/api/#retrieve-a-product
+func (c *Client) GetProductWithOptions(productCode string, options ProductOption) (Product, error) {
+	return c.GetProductWithOptionsContext(context.Background(), productCode, options)
+}
+
+// GetProductWithOptionsContext is like GetProductWithOptions but uses ctx for cancellation and deadlines
+func (c *Client) GetProductWithOptionsContext(ctx context.Context, productCode string, options ProductOption) (Product, error) {
 	var product Product
 
-	err := c.do(fmt.Sprintf("products/%s/", productCode), &product)
+	apiURL, err := url.Parse(fmt.Sprintf("products/%s/", url.PathEscape(productCode)))
 	if err != nil {
-		return Product{}, errors.Errorf("error retrieving the product: %v", err)
+		return Product{}, errors.Wrap(err, "unable to parse request url")
+	}
+
+	if !options.TariffsActiveAt.IsZero() {
+		q := apiURL.Query()
+		q.Add("tariffs_active_at", options.TariffsActiveAt.Format(iso8601))
+		apiURL.RawQuery = q.Encode()
+	}
+
+	err = c.do(ctx, apiURL.String(), &product)
+	if err != nil {
+		return Product{}, errors.Wrap(err, "error retrieving the product")
 	}
 
 	return product, nil
 }
 
-// urlAddUsername adds username to URL
-func urlAddUsername(URL, username string) (string, error) {
-	u, err := url.Parse(URL)
+// tariffChargesSeq returns an iterator over a given charge for a tariff
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) tariffChargesSeq(ctx context.Context, fuel, productCode, tariffCode, charge string, options RateOption) func(yield func(Rate, error) bool) {
+	apiURL, err := url.Parse(fmt.Sprintf("products/%s/%s-tariffs/%s/%s/", url.PathEscape(productCode), fuel, url.PathEscape(tariffCode), charge))
 	if err != nil {
-		return "", errors.Errorf("error parsing url: %v", err)
+		return seqError[Rate](errors.Wrap(err, "unable to parse request url"))
 	}
 
-	u.User = url.UserPassword(username, "")
-	return u.String(), nil
+	// Add options to URL if they are provided
+	if options != (RateOption{}) {
+		q := apiURL.Query()
+		if options.PageSize != 0 {
+			q.Add("page_size", strconv.Itoa(options.PageSize))
+		}
+		if !options.From.IsZero() {
+			q.Add("period_from", options.From.Format(iso8601))
+		}
+		if !options.To.IsZero() {
+			q.Add("period_to", options.To.Format(iso8601))
+		}
+		apiURL.RawQuery = q.Encode()
+	}
+
+	return paginate[Rate](ctx, c, apiURL.String(), "error retrieving "+charge)
 }
 
-func (c *Client) do(path string, v interface{}) error {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s", c.URL, path))
+// getTariffCharges retrieves all pages of a given charge for a tariff
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) getTariffCharges(ctx context.Context, fuel, productCode, tariffCode, charge string, options RateOption) ([]Rate, error) {
+	rates, err := collect(c.tariffChargesSeq(ctx, fuel, productCode, tariffCode, charge, options))
 	if err != nil {
-		return errors.Errorf("http get error: %v", err)
+		return nil, err
+	}
+
+	// API returns the most recent rates first
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].ValidFrom.Before(rates[j].ValidFrom)
+	})
+
+	return rates, nil
+}
+
+// GetElecStandardUnitRates retrieves standard unit rates of an electricity tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetElecStandardUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetElecStandardUnitRatesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetElecStandardUnitRatesContext is like GetElecStandardUnitRates but uses ctx for cancellation and deadlines
+func (c *Client) GetElecStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelElectricity, productCode, tariffCode, chargeStandardUnitRates, options)
+}
+
+// GetGasStandardUnitRates retrieves standard unit rates of a gas tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetGasStandardUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetGasStandardUnitRatesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetGasStandardUnitRatesContext is like GetGasStandardUnitRates but uses ctx for cancellation and deadlines
+func (c *Client) GetGasStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelGas, productCode, tariffCode, chargeStandardUnitRates, options)
+}
+
+// GetElecDayUnitRates retrieves day unit rates of a dual register electricity tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetElecDayUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetElecDayUnitRatesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetElecDayUnitRatesContext is like GetElecDayUnitRates but uses ctx for cancellation and deadlines
+func (c *Client) GetElecDayUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelElectricity, productCode, tariffCode, chargeDayUnitRates, options)
+}
+
+// GetElecNightUnitRates retrieves night unit rates of a dual register electricity tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetElecNightUnitRates(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetElecNightUnitRatesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetElecNightUnitRatesContext is like GetElecNightUnitRates but uses ctx for cancellation and deadlines
+func (c *Client) GetElecNightUnitRatesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelElectricity, productCode, tariffCode, chargeNightUnitRates, options)
+}
+
+// GetElecStandingCharges retrieves standing charge history of an electricity tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetElecStandingCharges(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetElecStandingChargesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetElecStandingChargesContext is like GetElecStandingCharges but uses ctx for cancellation and deadlines
+func (c *Client) GetElecStandingChargesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelElectricity, productCode, tariffCode, chargeStandingCharges, options)
+}
+
+// GetGasStandingCharges retrieves standing charge history of a gas tariff,
+// ordered by ValidFrom
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GetGasStandingCharges(productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.GetGasStandingChargesContext(context.Background(), productCode, tariffCode, options)
+}
+
+// GetGasStandingChargesContext is like GetGasStandingCharges but uses ctx for cancellation and deadlines
+func (c *Client) GetGasStandingChargesContext(ctx context.Context, productCode, tariffCode string, options RateOption) ([]Rate, error) {
+	return c.getTariffCharges(ctx, fuelGas, productCode, tariffCode, chargeStandingCharges, options)
+}
+
+// ElecStandardUnitRatesSeq returns an iterator over standard unit rates of an electricity tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) ElecStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelElectricity, productCode, tariffCode, chargeStandardUnitRates, options)
+}
+
+// GasStandardUnitRatesSeq returns an iterator over standard unit rates of a gas tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GasStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelGas, productCode, tariffCode, chargeStandardUnitRates, options)
+}
+
+// ElecDayUnitRatesSeq returns an iterator over day unit rates of a dual register electricity tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) ElecDayUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelElectricity, productCode, tariffCode, chargeDayUnitRates, options)
+}
+
+// ElecNightUnitRatesSeq returns an iterator over night unit rates of a dual register electricity tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) ElecNightUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelElectricity, productCode, tariffCode, chargeNightUnitRates, options)
+}
+
+// ElecStandingChargesSeq returns an iterator over standing charges of an electricity tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) ElecStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelElectricity, productCode, tariffCode, chargeStandingCharges, options)
+}
+
+// GasStandingChargesSeq returns an iterator over standing charges of a gas tariff,
+// most recent first, retrieving pages as needed
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+func (c *Client) GasStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options RateOption) func(yield func(Rate, error) bool) {
+	return c.tariffChargesSeq(ctx, fuelGas, productCode, tariffCode, chargeStandingCharges, options)
+}
+
+// GetAccount retrieves an account with its properties, meter points and agreements
+// https://developer.octopus.energy/docs/api/#accounts
+func (c *Client) GetAccount(accountNumber string) (Account, error) {
+	return c.GetAccountContext(context.Background(), accountNumber)
+}
+
+// GetAccountContext is like GetAccount but uses ctx for cancellation and deadlines
+func (c *Client) GetAccountContext(ctx context.Context, accountNumber string) (Account, error) {
+	// Dot segments would escape the account path, even if escaped
+	if accountNumber == "" || accountNumber == "." || accountNumber == ".." {
+		return Account{}, errors.Errorf("invalid account number %q", accountNumber)
+	}
+
+	var account Account
+
+	err := c.do(ctx, fmt.Sprintf("accounts/%s/", url.PathEscape(accountNumber)), &account)
+	if err != nil {
+		return Account{}, errors.Wrap(err, "error retrieving the account")
+	}
+
+	return account, nil
+}
+
+// ProductCode returns code of the product the agreement's tariff belongs to,
+// e.g. AGILE-18-02-21 for tariff E-1R-AGILE-18-02-21-C
+func (a Agreement) ProductCode() string {
+	parts := strings.Split(a.TariffCode, "-")
+	if len(parts) < 4 {
+		return ""
+	}
+
+	return strings.Join(parts[2:len(parts)-1], "-")
+}
+
+// Active checks if the agreement is in force at a given time
+func (a Agreement) Active(t time.Time) bool {
+	return !t.Before(a.ValidFrom) && (a.ValidTo.IsZero() || t.Before(a.ValidTo))
+}
+
+// nextPath converts a link to the next page into a path relative to the
+// base URL. Links pointing either to the configured base URL or to the
+// API itself (e.g. when the base URL is a proxy) are supported. The path of
+// a base URL with the same scheme and host as the link is stripped, or else
+// the longest matching path of a base URL (e.g. links of a recorded server).
+func (c *Client) nextPath(next string) string {
+	if next == "" {
+		return ""
+	}
+
+	u, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+
+	path := u.EscapedPath()
+	prefix := func(sameHost bool) (string, bool) {
+		var longest string
+		var found bool
+		for _, base := range []string{c.baseURL, defaultBaseURL} {
+			b, err := url.Parse(base)
+			if err != nil {
+				continue
+			}
+			if sameHost && (u.Scheme != b.Scheme || u.Host != b.Host) {
+				continue
+			}
+
+			if p := strings.TrimSuffix(b.EscapedPath(), "/") + "/"; strings.HasPrefix(path, p) && len(p) > len(longest) {
+				longest, found = p, true
+			}
+		}
+
+		return longest, found
+	}
+
+	p, ok := prefix(true)
+	if !ok {
+		p, _ = prefix(false)
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, p), "/")
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("http error - code %d received", resp.StatusCode)
+	return path
+}
+
+// String implements fmt.Stringer, without revealing the API key
+func (c *Client) String() string {
+	return fmt.Sprintf("octopusenergyapi.Client{URL: %s}", c.baseURL)
+}
+
+// GoString implements fmt.GoStringer, without revealing the API key
+func (c *Client) GoString() string {
+	return c.String()
+}
+
+// logf logs a message if the client has a logger
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
 	}
+}
+
+// do retrieves path and decodes the JSON response into v. Responses
+// are served from the client's cache when possible.
+func (c *Client) do(ctx context.Context, path string, v interface{}) error {
+	ttl := c.cacheTTL(path)
+
+	var entry CacheEntry
+	var cached bool
+	if ttl > 0 {
+		entry, cached = c.cache.Get(c.cacheKey(path))
+		if cached && time.Now().Before(entry.Expires) {
+			return decodeJSON(entry.Body, v)
+		}
+	}
+
+	// Revalidate stale cache entry
+	header := http.Header{}
+	if cached {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := c.fetch(ctx, path, header)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		entry = CacheEntry{
+			Body:         resp.Body,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+	}
+
+	if err := decodeJSON(entry.Body, v); err != nil {
+		return err
+	}
+
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+		if err := c.cache.Set(c.cacheKey(path), entry); err != nil {
+			c.logf("unable to cache %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
 
-	if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
-		return errors.Errorf("unable to unmarshal json: %v", err)
+// decodeJSON decodes JSON data into v
+func decodeJSON(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, "unable to unmarshal json")
 	}
 
 	return nil
 }
+
+// response represents a successful response
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// fetch retrieves path, retrying according to the client's retry policy
+func (c *Client) fetch(ctx context.Context, path string, header http.Header) (response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.fetchOnce(ctx, path, header)
+		if err == nil || attempt >= c.retry.MaxAttempts || !isRetryable(ctx, err) {
+			return resp, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+
+		delay, ok := c.retry.backoff(attempt, retryAfter)
+		if !ok {
+			return resp, err
+		}
+		c.logf("retrying %s in %s: %v", path, delay, err)
+
+		if err := sleep(ctx, delay); err != nil {
+			return response{}, errors.Wrap(err, "http get error")
+		}
+	}
+}
+
+// fetchOnce makes a single attempt to retrieve path
+func (c *Client) fetchOnce(ctx context.Context, path string, header http.Header) (response, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return response{}, errors.Wrap(err, "rate limiter")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", c.baseURL, path), nil)
+	if err != nil {
+		return response{}, errors.Wrap(err, "unable to create request")
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	// API key is sent as username, with empty password
+	req.SetBasicAuth(c.apiKey, "")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return response{}, errors.Wrap(err, "http get error")
+	}
+	defer resp.Body.Close()
+
+	// Not Modified is only expected for conditional requests
+	conditional := header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != ""
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusNotModified && conditional) {
+		return response{}, newAPIError(resp, path)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response{}, errors.Wrap(err, "http get error")
+	}
+
+	return response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
@@ -8,10 +8,12 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,31 +34,6 @@ func TestCheckPostcode(t *testing.T) {
 	}
 }
 
-func TestUrlAddUsername(t *testing.T) {
-	tests := []struct {
-		URL         string
-		username    string
-		expected    string
-		errExpected bool
-	}{
-		{"http://www.google.com/", "user", "http://user:@www.google.com/", false},
-		{"https://www.google.com/", "user", "https://user:@www.google.com/", false},
-		{"10928301####$$$%%", "user", "", true},
-	}
-
-	for _, test := range tests {
-		out, err := urlAddUsername(test.URL, test.username)
-
-		if test.errExpected {
-			assert.NotNil(t, err)
-		} else {
-			if assert.Nil(t, err) {
-				assert.Equal(t, test.expected, out)
-			}
-		}
-	}
-}
-
 func TestNewClient(t *testing.T) {
 	t.Run("pass", func(t *testing.T) {
 		c, err := NewClient("testapikey", http.DefaultClient)
@@ -65,6 +42,15 @@ func TestNewClient(t *testing.T) {
 		}
 	})
 
+	t.Run("apikey_not_exposed", func(t *testing.T) {
+		c, err := NewClient("testapikey", http.DefaultClient)
+		if assert.Nil(t, err) {
+			for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+				assert.NotContains(t, fmt.Sprintf(format, c), "testapikey", format)
+			}
+		}
+	})
+
 	t.Run("empty_apikey_error", func(t *testing.T) {
 		_, err := NewClient("", http.DefaultClient)
 		if assert.NotNil(t, err) {
@@ -131,6 +117,41 @@ func TestListProducts(t *testing.T) {
 	})
 }
 
+func TestListProductsOption(t *testing.T) {
+	availableAt, err := time.Parse(time.RFC3339, "2020-11-28T12:00:00Z")
+	assert.Nil(t, err)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		assert.Equal(t, "/v1/products/", r.URL.Path)
+		assert.Equal(t, "true", q.Get("is_green"))
+		assert.Equal(t, "false", q.Get("is_business"))
+		assert.Equal(t, "OCTOPUS_ENERGY", q.Get("brand"))
+		assert.Equal(t, availableAt.Format(iso8601), q.Get("available_at"))
+		for _, name := range []string{"is_variable", "is_tracker", "is_prepay"} {
+			_, ok := q[name]
+			assert.False(t, ok, name)
+		}
+
+		_, err := w.Write([]byte(`{"count":0,"next":null,"previous":null,"results":[]}`))
+		assert.Nil(t, err)
+	})
+	httpClient, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	client, err := NewClient("fakeapikey", httpClient)
+	if assert.Nil(t, err) {
+		_, err = client.ListProductsWithOptions(ListProductsOption{
+			IsGreen:     Bool(true),
+			IsBusiness:  Bool(false),
+			AvailableAt: availableAt,
+			Brand:       "OCTOPUS_ENERGY",
+		})
+		assert.Nil(t, err)
+	}
+}
+
 func TestGetProduct(t *testing.T) {
 	t.Run("pass", func(t *testing.T) {
 		f, err := os.Open("./testdata/getproduct.json")
@@ -160,6 +181,57 @@ func TestGetProduct(t *testing.T) {
 
 			assert.Len(t, product.SingleRegisterElecTariffs, 14)
 			assert.Len(t, product.DualRegisterElecTariffs, 14)
+
+			tariff := product.DualRegisterElecTariffs["_A"]["direct_debit_monthly"]
+			assert.Equal(t, "E-2R-VAR-17-01-11-A", tariff.Code)
+			assert.Equal(t, float32(20.64), tariff.StandingChargeExcVAT)
+			assert.Len(t, tariff.Links, 3)
+			assert.Equal(t, float32(16.87), tariff.DayUnitRateExcVAT)
+			assert.Equal(t, float32(17.7135), tariff.DayUnitRateIncVAT)
+			assert.Equal(t, float32(9.91), tariff.NightUnitRateExcVAT)
+			assert.Equal(t, float32(10.4055), tariff.NightUnitRateIncVAT)
+		}
+	})
+
+	t.Run("tariffs_active_at", func(t *testing.T) {
+		activeAt, err := time.Parse(time.RFC3339, "2020-11-28T12:32:16Z")
+		assert.Nil(t, err)
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/products/VAR-17-01-11/", r.URL.Path)
+			assert.Equal(t, activeAt.Format(iso8601), r.URL.Query().Get("tariffs_active_at"))
+
+			data, err := os.ReadFile("./testdata/getproduct.json")
+			assert.Nil(t, err)
+			_, err = w.Write(data)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			product, err := client.GetProductWithOptions("VAR-17-01-11", ProductOption{TariffsActiveAt: activeAt})
+			if assert.Nil(t, err) {
+				assert.Equal(t, activeAt, product.TariffsActiveAt.Truncate(time.Second))
+				assert.Equal(t, "OCTOPUS_ENERGY", product.Brand)
+			}
+		}
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/products/VAR%2F..%2F%3Fx=1%23/", r.URL.EscapedPath())
+			assert.Empty(t, r.URL.Query().Get("x"))
+			w.WriteHeader(http.StatusNotFound)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetProductWithOptions("VAR/../?x=1#", ProductOption{})
+			assert.True(t, errors.Is(err, ErrNotFound))
 		}
 	})
 
@@ -253,6 +325,7 @@ func TestGetGridSupplyPoint(t *testing.T) {
 		defer f.Close()
 
 		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "SW1A1AA", r.URL.Query().Get("postcode"))
 			_, err = io.Copy(w, f)
 			assert.Nil(t, err)
 		})
@@ -269,6 +342,25 @@ func TestGetGridSupplyPoint(t *testing.T) {
 		}
 	})
 
+	t.Run("escaped", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, url.Values{"postcode": {"GIR0AA&page=2#x"}}, r.URL.Query())
+
+			data, err := os.ReadFile("./testdata/getgridsupplypoint.json")
+			assert.Nil(t, err)
+			_, err = w.Write(data)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetGridSupplyPoint("GIR 0AA&page=2#x")
+			assert.Nil(t, err)
+		}
+	})
+
 	t.Run("many_gsp_error", func(t *testing.T) {
 		f, err := os.Open("./testdata/getgridsupplypoint_err.json")
 		assert.Nil(t, err)
@@ -363,20 +455,23 @@ func TestGetMeterConsumption(t *testing.T) {
 		}
 
 		t.Run("pass_electricity", func(t *testing.T) {
-			f, err := os.Open("./testdata/consumption_elec.json")
-			assert.Nil(t, err)
-			defer f.Close()
-
 			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				q := r.URL.Query()
 
-				assert.Equal(t, options.From.Format(iso8601), q.Get("period_from"))
-				assert.Equal(t, options.To.Format(iso8601), q.Get("period_to"))
-				assert.Equal(t, fmt.Sprint(pageSize), q.Get("page_size"))
-				assert.Equal(t, options.OrderBy, q.Get("order_by"))
-				assert.Equal(t, options.GroupBy, q.Get("group_by"))
+				// Second page is requested from the link returned by the API
+				file := "./testdata/consumption_elec_page2.json"
+				if q.Get("page") == "" {
+					assert.Equal(t, options.From.Format(iso8601), q.Get("period_from"))
+					assert.Equal(t, options.To.Format(iso8601), q.Get("period_to"))
+					assert.Equal(t, fmt.Sprint(pageSize), q.Get("page_size"))
+					assert.Equal(t, options.OrderBy, q.Get("order_by"))
+					assert.Equal(t, options.GroupBy, q.Get("group_by"))
+					file = "./testdata/consumption_elec.json"
+				}
 
-				_, err = io.Copy(w, f)
+				data, err := os.ReadFile(file)
+				assert.Nil(t, err)
+				_, err = w.Write(data)
 				assert.Nil(t, err)
 			})
 			httpClient, teardown := testingHTTPClient(h)
@@ -384,26 +479,37 @@ func TestGetMeterConsumption(t *testing.T) {
 
 			client, err := NewClient("fakeapikey", httpClient)
 			if assert.Nil(t, err) {
-				_, err = client.GetElecMeterConsumption(mpan, serialNo, options)
-				assert.Nil(t, err)
+				cons, err := client.GetElecMeterConsumption(mpan, serialNo, options)
+				if assert.Nil(t, err) {
+					assert.Len(t, cons, 102)
+				}
+
+				page, err := client.GetElecMeterConsumptionAll(mpan, serialNo, options)
+				if assert.Nil(t, err) {
+					assert.Equal(t, 21072, page.Count)
+					assert.Len(t, page.Results, 102)
+				}
 			}
 		})
 
 		t.Run("pass_gas", func(t *testing.T) {
-			f, err := os.Open("./testdata/consumption_gas.json")
-			assert.Nil(t, err)
-			defer f.Close()
-
 			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				q := r.URL.Query()
 
-				assert.Equal(t, options.From.Format(iso8601), q.Get("period_from"))
-				assert.Equal(t, options.To.Format(iso8601), q.Get("period_to"))
-				assert.Equal(t, fmt.Sprint(pageSize), q.Get("page_size"))
-				assert.Equal(t, options.OrderBy, q.Get("order_by"))
-				assert.Equal(t, options.GroupBy, q.Get("group_by"))
+				// Second page is requested from the link returned by the API
+				file := "./testdata/consumption_gas_page2.json"
+				if q.Get("page") == "" {
+					assert.Equal(t, options.From.Format(iso8601), q.Get("period_from"))
+					assert.Equal(t, options.To.Format(iso8601), q.Get("period_to"))
+					assert.Equal(t, fmt.Sprint(pageSize), q.Get("page_size"))
+					assert.Equal(t, options.OrderBy, q.Get("order_by"))
+					assert.Equal(t, options.GroupBy, q.Get("group_by"))
+					file = "./testdata/consumption_gas.json"
+				}
 
-				_, err = io.Copy(w, f)
+				data, err := os.ReadFile(file)
+				assert.Nil(t, err)
+				_, err = w.Write(data)
 				assert.Nil(t, err)
 			})
 			httpClient, teardown := testingHTTPClient(h)
@@ -411,11 +517,59 @@ func TestGetMeterConsumption(t *testing.T) {
 
 			client, err := NewClient("fakeapikey", httpClient)
 			if assert.Nil(t, err) {
-				_, err = client.GetGasMeterConsumption(mpan, serialNo, options)
-				assert.Nil(t, err)
+				cons, err := client.GetGasMeterConsumption(mpan, serialNo, options)
+				if assert.Nil(t, err) {
+					assert.Len(t, cons, 102)
+				}
+
+				page, err := client.GetGasMeterConsumptionAll(mpan, serialNo, options)
+				if assert.Nil(t, err) {
+					assert.Equal(t, 21072, page.Count)
+					assert.Len(t, page.Results, 102)
+				}
+			}
+		})
+
+	})
+
+	t.Run("page", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "2", r.URL.Query().Get("page"))
+			assert.Equal(t, "100", r.URL.Query().Get("page_size"))
+
+			data, err := os.ReadFile("./testdata/consumption_elec_page2.json")
+			assert.Nil(t, err)
+			_, err = w.Write(data)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			page, err := client.GetElecMeterConsumptionPage(mpan, serialNo, ConsumptionOption{PageSize: 100}, 2)
+			if assert.Nil(t, err) {
+				assert.Equal(t, 21072, page.Count)
+				assert.Equal(t, "", page.Next)
+				assert.Len(t, page.Results, 2)
 			}
+		}
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/electricity-meter-points/0123%2F..%2F/meters/SER%3Fpage_size=1&x=/consumption/", r.URL.EscapedPath())
+			assert.Equal(t, "page=2", r.URL.RawQuery)
+			w.WriteHeader(http.StatusNotFound)
 		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
 
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetElecMeterConsumptionPage("0123/../", "SER?page_size=1&x=", ConsumptionOption{}, 2)
+			assert.True(t, errors.Is(err, ErrNotFound))
+		}
 	})
 
 	t.Run("fail", func(t *testing.T) {
@@ -424,6 +578,11 @@ func TestGetMeterConsumption(t *testing.T) {
 
 		client, err := NewClient("fakeapikey", httpClient)
 		if assert.Nil(t, err) {
+			_, err = client.GetElecMeterConsumptionPage(mpan, serialNo, ConsumptionOption{}, 1)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
+
 			_, err = client.GetElecMeterConsumption(mpan, serialNo, ConsumptionOption{})
 			if assert.NotNil(t, err) {
 				assert.Contains(t, err.Error(), "error retrieving")
@@ -433,6 +592,11 @@ func TestGetMeterConsumption(t *testing.T) {
 			if assert.NotNil(t, err) {
 				assert.Contains(t, err.Error(), "error retrieving")
 			}
+
+			_, err = client.GetElecMeterConsumptionAll(mpan, serialNo, ConsumptionOption{})
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
 		}
 	})
 }
@@ -449,13 +613,34 @@ func TestDo(t *testing.T) {
 		client, err := NewClient("fakeapikey", httpClient)
 		if assert.Nil(t, err) {
 			var out interface{}
-			err = client.do("testpath", out)
+			err = client.do(context.Background(), "testpath", out)
 			if assert.Nil(t, err) {
 				assert.Equal(t, nil, out)
 			}
 		}
 	})
 
+	t.Run("authorization", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if assert.True(t, ok) {
+				assert.Equal(t, "fakeapikey", username)
+				assert.Equal(t, "", password)
+			}
+
+			_, err := w.Write([]byte("[]"))
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			var out interface{}
+			assert.Nil(t, client.do(context.Background(), "testpath", &out))
+		}
+	})
+
 	t.Run("httpget_error", func(t *testing.T) {
 		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		})
@@ -464,9 +649,10 @@ func TestDo(t *testing.T) {
 
 		client, err := NewClient("fakeapikey", httpClient)
 		if assert.Nil(t, err) {
-			err = client.do("testpath", nil)
+			err = client.do(context.Background(), "testpath", nil)
 			if assert.NotNil(t, err) {
 				assert.Contains(t, err.Error(), "http get error")
+				assert.NotContains(t, fmt.Sprintf("%+v", err), "fakeapikey")
 			}
 		}
 	})
@@ -480,7 +666,7 @@ func TestDo(t *testing.T) {
 
 		client, err := NewClient("fakeapikey", httpClient)
 		if assert.Nil(t, err) {
-			err = client.do("testpath", nil)
+			err = client.do(context.Background(), "testpath", nil)
 			if assert.NotNil(t, err) {
 				assert.Contains(t, err.Error(), "http error")
 			}
@@ -501,10 +687,425 @@ func TestDo(t *testing.T) {
 
 		client, err := NewClient("fakeapikey", httpClient)
 		if assert.Nil(t, err) {
-			err = client.do("testpath", nil)
+			err = client.do(context.Background(), "testpath", nil)
 			if assert.NotNil(t, err) {
 				assert.Contains(t, err.Error(), "unmarshal json")
 			}
 		}
 	})
 }
+
+func TestGetStandardUnitRates(t *testing.T) {
+	productCode := "AGILE-18-02-21"
+	tariffCode := "E-1R-AGILE-18-02-21-C"
+
+	timeFrom, err := time.Parse(time.RFC3339, "2020-11-28T00:00:00Z")
+	assert.Nil(t, err)
+	timeTo, err := time.Parse(time.RFC3339, "2020-11-28T02:00:00Z")
+	assert.Nil(t, err)
+
+	options := RateOption{
+		From: timeFrom,
+		To:   timeTo,
+	}
+
+	t.Run("pass", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+
+			assert.Equal(t, fmt.Sprintf("/v1/products/%s/electricity-tariffs/%s/standard-unit-rates/", productCode, tariffCode), r.URL.Path)
+			assert.Equal(t, options.From.Format(iso8601), q.Get("period_from"))
+			assert.Equal(t, options.To.Format(iso8601), q.Get("period_to"))
+
+			file := "./testdata/standardunitrates.json"
+			if q.Get("page") == "2" {
+				file = "./testdata/standardunitrates_page2.json"
+			}
+
+			data, err := os.ReadFile(file)
+			assert.Nil(t, err)
+			_, err = w.Write(data)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			rates, err := client.GetElecStandardUnitRates(productCode, tariffCode, options)
+			if assert.Nil(t, err) && assert.Len(t, rates, 4) {
+				// Rates are returned in chronological order
+				assert.Equal(t, timeFrom, rates[0].ValidFrom.UTC())
+				assert.Equal(t, timeTo, rates[3].ValidTo.UTC())
+				assert.Equal(t, float32(12.6), rates[0].ValueExcVAT)
+				assert.Equal(t, float32(13.23), rates[0].ValueIncVAT)
+				assert.Equal(t, "DIRECT_DEBIT", rates[0].PaymentMethod)
+				assert.Equal(t, "", rates[3].PaymentMethod)
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		httpClient, teardown := testingHTTPClient(nil)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetElecStandardUnitRates(productCode, tariffCode, options)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
+
+			_, err = client.GetGasStandardUnitRates(productCode, tariffCode, options)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
+		}
+	})
+}
+
+func TestGetStandingCharges(t *testing.T) {
+	productCode := "VAR-17-01-11"
+	tariffCode := "G-1R-VAR-17-01-11-A"
+
+	t.Run("pass", func(t *testing.T) {
+		f, err := os.Open("./testdata/standingcharges.json")
+		assert.Nil(t, err)
+		defer f.Close()
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, fmt.Sprintf("/v1/products/%s/gas-tariffs/%s/standing-charges/", productCode, tariffCode), r.URL.Path)
+
+			_, err = io.Copy(w, f)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			charges, err := client.GetGasStandingCharges(productCode, tariffCode, RateOption{})
+			if assert.Nil(t, err) && assert.Len(t, charges, 3) {
+				// Charges are returned in chronological order
+				assert.Equal(t, float32(20.64), charges[0].ValueExcVAT)
+				assert.Equal(t, float32(21.672), charges[0].ValueIncVAT)
+				assert.Equal(t, charges[1].ValidFrom, charges[0].ValidTo)
+				assert.Equal(t, charges[2].ValidFrom, charges[1].ValidTo)
+				assert.True(t, charges[2].ValidTo.IsZero())
+				assert.Equal(t, "DIRECT_DEBIT", charges[2].PaymentMethod)
+			}
+		}
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/products/VAR%2F..%2Faccounts/gas-tariffs/G-1R%3Fx=1%23/standing-charges/", r.URL.EscapedPath())
+			assert.Empty(t, r.URL.Query().Get("x"))
+			w.WriteHeader(http.StatusNotFound)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetGasStandingCharges("VAR/../accounts", "G-1R?x=1#", RateOption{})
+			assert.True(t, errors.Is(err, ErrNotFound))
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		httpClient, teardown := testingHTTPClient(nil)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetElecStandingCharges(productCode, tariffCode, RateOption{})
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
+
+			_, err = client.GetGasStandingCharges(productCode, tariffCode, RateOption{})
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving")
+			}
+		}
+	})
+}
+
+func TestGetDayNightUnitRates(t *testing.T) {
+	productCode := "VAR-17-01-11"
+	tariffCode := "E-2R-VAR-17-01-11-A"
+
+	tests := []struct {
+		name   string
+		fn     func(*Client) ([]Rate, error)
+		charge string
+	}{
+		{"day", func(c *Client) ([]Rate, error) {
+			return c.GetElecDayUnitRates(productCode, tariffCode, RateOption{PageSize: 2})
+		}, "day-unit-rates"},
+		{"night", func(c *Client) ([]Rate, error) {
+			return c.GetElecNightUnitRates(productCode, tariffCode, RateOption{PageSize: 2})
+		}, "night-unit-rates"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Subsequent pages are requested from the link returned by the API
+				file := "./testdata/standardunitrates_page2.json"
+				if r.URL.Query().Get("page") == "" {
+					assert.Equal(t, fmt.Sprintf("/v1/products/%s/electricity-tariffs/%s/%s/", productCode, tariffCode, test.charge), r.URL.Path)
+					assert.Equal(t, "2", r.URL.Query().Get("page_size"))
+					file = "./testdata/standardunitrates.json"
+				}
+
+				data, err := os.ReadFile(file)
+				assert.Nil(t, err)
+				_, err = w.Write(data)
+				assert.Nil(t, err)
+			})
+			httpClient, teardown := testingHTTPClient(h)
+			defer teardown()
+
+			client, err := NewClient("fakeapikey", httpClient)
+			if assert.Nil(t, err) {
+				rates, err := test.fn(client)
+				if assert.Nil(t, err) {
+					assert.Len(t, rates, 4)
+				}
+			}
+		})
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("before_request", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request should not be made")
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetMeterPointContext(ctx, "0123456789")
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), context.Canceled.Error())
+			}
+		}
+	})
+
+	t.Run("mid_pagination", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		requests := 0
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			// Cancel the context once the first page has been served
+			data, err := os.ReadFile("./testdata/standardunitrates.json")
+			assert.Nil(t, err)
+			_, err = w.Write(data)
+			assert.Nil(t, err)
+			cancel()
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetElecStandardUnitRatesContext(ctx, "AGILE-18-02-21", "E-1R-AGILE-18-02-21-C", RateOption{})
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), context.Canceled.Error())
+			}
+			assert.Equal(t, 1, requests)
+		}
+	})
+}
+
+func TestGetAccount(t *testing.T) {
+	t.Run("pass", func(t *testing.T) {
+		f, err := os.Open("./testdata/getaccount.json")
+		assert.Nil(t, err)
+		defer f.Close()
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/accounts/A-1234ABCD/", r.URL.Path)
+
+			_, err = io.Copy(w, f)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		account, err := client.GetAccount("A-1234ABCD")
+		if assert.Nil(t, err) && assert.Len(t, account.Properties, 1) {
+			assert.Equal(t, "A-1234ABCD", account.Number)
+
+			property := account.Properties[0]
+			assert.Equal(t, "SW1A 2AA", property.Postcode)
+			assert.True(t, property.MovedOutAt.IsZero())
+
+			if assert.Len(t, property.ElecMeterPoints, 1) {
+				mp := property.ElecMeterPoints[0]
+				assert.Equal(t, "0123456789012", mp.MPAN)
+				assert.Equal(t, 1, mp.ProfileClass)
+				assert.Equal(t, "19L0123456", mp.Meters[0].SerialNumber)
+				assert.Equal(t, "STANDARD", mp.Meters[0].Registers[0].Rate)
+				assert.True(t, mp.Meters[0].Registers[0].IsSettlementRegister)
+
+				if assert.Len(t, mp.Agreements, 2) {
+					assert.Equal(t, "E-1R-AGILE-18-02-21-C", mp.Agreements[1].TariffCode)
+					assert.True(t, mp.Agreements[1].ValidTo.IsZero())
+				}
+			}
+
+			if assert.Len(t, property.GasMeterPoints, 1) {
+				mp := property.GasMeterPoints[0]
+				assert.Equal(t, "1234567890", mp.MPRN)
+				assert.Equal(t, "G4A01234567890", mp.Meters[0].SerialNumber)
+				assert.Equal(t, "G-1R-VAR-19-04-12-C", mp.Agreements[0].TariffCode)
+			}
+		}
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/accounts/..%2Fproducts%2F%3Fx=1/", r.URL.EscapedPath())
+			assert.Empty(t, r.URL.RawQuery)
+			w.WriteHeader(http.StatusNotFound)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetAccount("../products/?x=1")
+			assert.True(t, errors.Is(err, ErrNotFound))
+
+			for _, accountNumber := range []string{"", ".", ".."} {
+				_, err = client.GetAccount(accountNumber)
+				if assert.NotNil(t, err, accountNumber) {
+					assert.Contains(t, err.Error(), "invalid account number")
+				}
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		httpClient, teardown := testingHTTPClient(nil)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetAccount("A-1234ABCD")
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving the account")
+			}
+		}
+	})
+}
+
+func TestAgreement(t *testing.T) {
+	validFrom := time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC)
+	validTo := time.Date(2022, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		agreement   Agreement
+		productCode string
+		at          time.Time
+		active      bool
+	}{
+		{Agreement{"E-1R-AGILE-18-02-21-C", validFrom, time.Time{}}, "AGILE-18-02-21", validTo, true},
+		{Agreement{"E-2R-VAR-19-04-12-N", validFrom, validTo}, "VAR-19-04-12", validTo, false},
+		{Agreement{"G-1R-VAR-19-04-12-N", validFrom, validTo}, "VAR-19-04-12", validFrom, true},
+		{Agreement{"G-1R-VAR-19-04-12-N", validFrom, validTo}, "VAR-19-04-12", validFrom.Add(-time.Second), false},
+		{Agreement{"INVALID", validFrom, validTo}, "", validFrom, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.productCode, test.agreement.ProductCode(), test.agreement.TariffCode)
+		assert.Equal(t, test.active, test.agreement.Active(test.at), test.agreement.TariffCode)
+	}
+}
+
+func TestCheckMPRN(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1234567890", true},
+		{"123456", true},
+		{"12345", false},
+		{"12345678901", false},
+		{"12345A7890", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, CheckMPRN(test.input), test.input)
+	}
+}
+
+func TestGetGasMeterPoint(t *testing.T) {
+	t.Run("pass", func(t *testing.T) {
+		f, err := os.Open("./testdata/getgasmeterpoint.json")
+		assert.Nil(t, err)
+		defer f.Close()
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/gas-meter-points/1234567890/", r.URL.Path)
+
+			_, err = io.Copy(w, f)
+			assert.Nil(t, err)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			mp, err := client.GetGasMeterPoint("1234567890")
+			if assert.Nil(t, err) {
+				assert.Equal(t, "1234567890", mp.MPRN)
+				assert.Equal(t, 12000, mp.ConsumptionStandard)
+				if assert.Len(t, mp.Meters, 1) {
+					assert.Equal(t, "G4A01234567890", mp.Meters[0].SerialNumber)
+					assert.Equal(t, "STANDARD", mp.Meters[0].Registers[0].Rate)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid_mprn", func(t *testing.T) {
+		client, err := NewClient("fakeapikey", http.DefaultClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetGasMeterPoint("invalid")
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "invalid mprn")
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		httpClient, teardown := testingHTTPClient(nil)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient)
+		if assert.Nil(t, err) {
+			_, err = client.GetGasMeterPoint("1234567890")
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "error retrieving gas meterpoint")
+			}
+		}
+	})
+}
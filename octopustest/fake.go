@@ -0,0 +1,571 @@
+package octopustest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// ErrNotProgrammed is returned by Fake methods without a programmed response
+var ErrNotProgrammed = errors.New("response not programmed")
+
+// Call is a method call recorded by Fake
+type Call struct {
+	// Method is the name of the called method, e.g. GetMeterPointContext
+	Method string
+	// Args are the arguments of the call, excluding context
+	Args []interface{}
+}
+
+// Fake is an implementation of octopusenergyapi.API for tests. Responses
+// are programmed by setting the function fields; methods without a function
+// set return ErrNotProgrammed. Variants of a method (with context, Seq)
+// share the same function. Every call is recorded.
+//
+// Like the iterators of octopusenergyapi.Client, Seq methods do nothing
+// until iteration starts, when the call is recorded and the function called.
+// Results returned along with an error are yielded before the error, as if
+// retrieving a later page failed. Tariff charges should be programmed ordered
+// by ValidFrom, as returned by the Get methods of octopusenergyapi.Client;
+// the Seq methods yield them most recent first.
+//
+// Function fields should be set before the fake is used. Calls are safe for
+// concurrent use.
+type Fake struct {
+	GetMeterPointFunc      func(ctx context.Context, mpan string) (octopusenergyapi.MeterPoint, error)
+	GetGasMeterPointFunc   func(ctx context.Context, mprn string) (octopusenergyapi.GasMeterPoint, error)
+	GetGridSupplyPointFunc func(ctx context.Context, postcode string) (octopusenergyapi.GridSupplyPoint, error)
+	// GridSupplyPointsFunc is used by GridSupplyPointsSeq
+	GridSupplyPointsFunc func(ctx context.Context, postcode string) ([]octopusenergyapi.GridSupplyPoint, error)
+
+	GetElecMeterConsumptionFunc     func(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error)
+	GetElecMeterConsumptionPageFunc func(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error)
+	GetGasMeterConsumptionFunc      func(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error)
+	GetGasMeterConsumptionPageFunc  func(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error)
+
+	ListProductsFunc func(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error)
+	GetProductFunc   func(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error)
+
+	GetElecStandardUnitRatesFunc func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+	GetGasStandardUnitRatesFunc  func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+	GetElecDayUnitRatesFunc      func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+	GetElecNightUnitRatesFunc    func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+	GetElecStandingChargesFunc   func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+	GetGasStandingChargesFunc    func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+
+	GetAccountFunc func(ctx context.Context, accountNumber string) (octopusenergyapi.Account, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Fake has to implement every operation of the client
+var _ octopusenergyapi.API = (*Fake)(nil)
+
+// Calls returns all recorded calls, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns recorded calls of a method
+func (f *Fake) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []Call
+	for _, call := range f.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Reset clears recorded calls
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = nil
+}
+
+func (f *Fake) record(method string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+}
+
+// notProgrammed returns the error of a method without a response
+func notProgrammed(method string) error {
+	return errors.Wrap(ErrNotProgrammed, method)
+}
+
+// lazySeq returns a sequence calling fn when iteration starts. Items
+// returned by fn are yielded first, followed by its error if not nil.
+func lazySeq[T any](fn func() ([]T, error)) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		items, err := fn()
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// GetMeterPoint implements octopusenergyapi.API
+func (f *Fake) GetMeterPoint(mpan string) (octopusenergyapi.MeterPoint, error) {
+	f.record("GetMeterPoint", mpan)
+	return f.getMeterPoint(context.Background(), mpan)
+}
+
+// GetMeterPointContext implements octopusenergyapi.API
+func (f *Fake) GetMeterPointContext(ctx context.Context, mpan string) (octopusenergyapi.MeterPoint, error) {
+	f.record("GetMeterPointContext", mpan)
+	return f.getMeterPoint(ctx, mpan)
+}
+
+func (f *Fake) getMeterPoint(ctx context.Context, mpan string) (octopusenergyapi.MeterPoint, error) {
+	if f.GetMeterPointFunc == nil {
+		return octopusenergyapi.MeterPoint{}, notProgrammed("GetMeterPoint")
+	}
+
+	return f.GetMeterPointFunc(ctx, mpan)
+}
+
+// GetGasMeterPoint implements octopusenergyapi.API
+func (f *Fake) GetGasMeterPoint(mprn string) (octopusenergyapi.GasMeterPoint, error) {
+	f.record("GetGasMeterPoint", mprn)
+	return f.getGasMeterPoint(context.Background(), mprn)
+}
+
+// GetGasMeterPointContext implements octopusenergyapi.API
+func (f *Fake) GetGasMeterPointContext(ctx context.Context, mprn string) (octopusenergyapi.GasMeterPoint, error) {
+	f.record("GetGasMeterPointContext", mprn)
+	return f.getGasMeterPoint(ctx, mprn)
+}
+
+func (f *Fake) getGasMeterPoint(ctx context.Context, mprn string) (octopusenergyapi.GasMeterPoint, error) {
+	if f.GetGasMeterPointFunc == nil {
+		return octopusenergyapi.GasMeterPoint{}, notProgrammed("GetGasMeterPoint")
+	}
+
+	return f.GetGasMeterPointFunc(ctx, mprn)
+}
+
+// GetGridSupplyPoint implements octopusenergyapi.API
+func (f *Fake) GetGridSupplyPoint(postcode string) (octopusenergyapi.GridSupplyPoint, error) {
+	f.record("GetGridSupplyPoint", postcode)
+	return f.getGridSupplyPoint(context.Background(), postcode)
+}
+
+// GetGridSupplyPointContext implements octopusenergyapi.API
+func (f *Fake) GetGridSupplyPointContext(ctx context.Context, postcode string) (octopusenergyapi.GridSupplyPoint, error) {
+	f.record("GetGridSupplyPointContext", postcode)
+	return f.getGridSupplyPoint(ctx, postcode)
+}
+
+func (f *Fake) getGridSupplyPoint(ctx context.Context, postcode string) (octopusenergyapi.GridSupplyPoint, error) {
+	if f.GetGridSupplyPointFunc == nil {
+		return octopusenergyapi.GridSupplyPoint{}, notProgrammed("GetGridSupplyPoint")
+	}
+
+	return f.GetGridSupplyPointFunc(ctx, postcode)
+}
+
+// GridSupplyPointsSeq implements octopusenergyapi.API
+func (f *Fake) GridSupplyPointsSeq(ctx context.Context, postcode string) func(yield func(octopusenergyapi.GridSupplyPoint, error) bool) {
+	return lazySeq(func() ([]octopusenergyapi.GridSupplyPoint, error) {
+		f.record("GridSupplyPointsSeq", postcode)
+
+		if f.GridSupplyPointsFunc == nil {
+			return nil, notProgrammed("GridSupplyPointsSeq")
+		}
+
+		return f.GridSupplyPointsFunc(ctx, postcode)
+	})
+}
+
+// GetElecMeterConsumption implements octopusenergyapi.API
+func (f *Fake) GetElecMeterConsumption(mpan, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	f.record("GetElecMeterConsumption", mpan, serialNo, options)
+	return f.getElecMeterConsumption(context.Background(), mpan, serialNo, options)
+}
+
+// GetElecMeterConsumptionContext implements octopusenergyapi.API
+func (f *Fake) GetElecMeterConsumptionContext(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	f.record("GetElecMeterConsumptionContext", mpan, serialNo, options)
+	return f.getElecMeterConsumption(ctx, mpan, serialNo, options)
+}
+
+// ElecMeterConsumptionSeq implements octopusenergyapi.API
+func (f *Fake) ElecMeterConsumptionSeq(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) func(yield func(octopusenergyapi.Consumption, error) bool) {
+	return lazySeq(func() ([]octopusenergyapi.Consumption, error) {
+		f.record("ElecMeterConsumptionSeq", mpan, serialNo, options)
+		return f.getElecMeterConsumption(ctx, mpan, serialNo, options)
+	})
+}
+
+// GetElecMeterConsumptionAll implements octopusenergyapi.API.
+// Count of the returned page is the number of programmed results.
+func (f *Fake) GetElecMeterConsumptionAll(mpan, serialNo string, options octopusenergyapi.ConsumptionOption) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetElecMeterConsumptionAll", mpan, serialNo, options)
+	return consumptionPage(f.getElecMeterConsumption(context.Background(), mpan, serialNo, options))
+}
+
+// GetElecMeterConsumptionAllContext implements octopusenergyapi.API
+func (f *Fake) GetElecMeterConsumptionAllContext(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetElecMeterConsumptionAllContext", mpan, serialNo, options)
+	return consumptionPage(f.getElecMeterConsumption(ctx, mpan, serialNo, options))
+}
+
+func (f *Fake) getElecMeterConsumption(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	if f.GetElecMeterConsumptionFunc == nil {
+		return nil, notProgrammed("GetElecMeterConsumption")
+	}
+
+	return f.GetElecMeterConsumptionFunc(ctx, mpan, serialNo, options)
+}
+
+// GetElecMeterConsumptionPage implements octopusenergyapi.API
+func (f *Fake) GetElecMeterConsumptionPage(mpan, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetElecMeterConsumptionPage", mpan, serialNo, options, page)
+	return f.getElecMeterConsumptionPage(context.Background(), mpan, serialNo, options, page)
+}
+
+// GetElecMeterConsumptionPageContext implements octopusenergyapi.API
+func (f *Fake) GetElecMeterConsumptionPageContext(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetElecMeterConsumptionPageContext", mpan, serialNo, options, page)
+	return f.getElecMeterConsumptionPage(ctx, mpan, serialNo, options, page)
+}
+
+func (f *Fake) getElecMeterConsumptionPage(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	if f.GetElecMeterConsumptionPageFunc == nil {
+		return octopusenergyapi.ConsumptionPage{}, notProgrammed("GetElecMeterConsumptionPage")
+	}
+
+	return f.GetElecMeterConsumptionPageFunc(ctx, mpan, serialNo, options, page)
+}
+
+// GetGasMeterConsumption implements octopusenergyapi.API
+func (f *Fake) GetGasMeterConsumption(mprn, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	f.record("GetGasMeterConsumption", mprn, serialNo, options)
+	return f.getGasMeterConsumption(context.Background(), mprn, serialNo, options)
+}
+
+// GetGasMeterConsumptionContext implements octopusenergyapi.API
+func (f *Fake) GetGasMeterConsumptionContext(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	f.record("GetGasMeterConsumptionContext", mprn, serialNo, options)
+	return f.getGasMeterConsumption(ctx, mprn, serialNo, options)
+}
+
+// GasMeterConsumptionSeq implements octopusenergyapi.API
+func (f *Fake) GasMeterConsumptionSeq(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) func(yield func(octopusenergyapi.Consumption, error) bool) {
+	return lazySeq(func() ([]octopusenergyapi.Consumption, error) {
+		f.record("GasMeterConsumptionSeq", mprn, serialNo, options)
+		return f.getGasMeterConsumption(ctx, mprn, serialNo, options)
+	})
+}
+
+// GetGasMeterConsumptionAll implements octopusenergyapi.API.
+// Count of the returned page is the number of programmed results.
+func (f *Fake) GetGasMeterConsumptionAll(mprn, serialNo string, options octopusenergyapi.ConsumptionOption) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetGasMeterConsumptionAll", mprn, serialNo, options)
+	return consumptionPage(f.getGasMeterConsumption(context.Background(), mprn, serialNo, options))
+}
+
+// GetGasMeterConsumptionAllContext implements octopusenergyapi.API
+func (f *Fake) GetGasMeterConsumptionAllContext(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetGasMeterConsumptionAllContext", mprn, serialNo, options)
+	return consumptionPage(f.getGasMeterConsumption(ctx, mprn, serialNo, options))
+}
+
+func (f *Fake) getGasMeterConsumption(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+	if f.GetGasMeterConsumptionFunc == nil {
+		return nil, notProgrammed("GetGasMeterConsumption")
+	}
+
+	return f.GetGasMeterConsumptionFunc(ctx, mprn, serialNo, options)
+}
+
+// consumptionPage returns consumption as a single page of all results
+func consumptionPage(results []octopusenergyapi.Consumption, err error) (octopusenergyapi.ConsumptionPage, error) {
+	if err != nil {
+		return octopusenergyapi.ConsumptionPage{}, err
+	}
+
+	return octopusenergyapi.ConsumptionPage{Count: len(results), Results: results}, nil
+}
+
+// GetGasMeterConsumptionPage implements octopusenergyapi.API
+func (f *Fake) GetGasMeterConsumptionPage(mprn, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetGasMeterConsumptionPage", mprn, serialNo, options, page)
+	return f.getGasMeterConsumptionPage(context.Background(), mprn, serialNo, options, page)
+}
+
+// GetGasMeterConsumptionPageContext implements octopusenergyapi.API
+func (f *Fake) GetGasMeterConsumptionPageContext(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	f.record("GetGasMeterConsumptionPageContext", mprn, serialNo, options, page)
+	return f.getGasMeterConsumptionPage(ctx, mprn, serialNo, options, page)
+}
+
+func (f *Fake) getGasMeterConsumptionPage(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption, page int) (octopusenergyapi.ConsumptionPage, error) {
+	if f.GetGasMeterConsumptionPageFunc == nil {
+		return octopusenergyapi.ConsumptionPage{}, notProgrammed("GetGasMeterConsumptionPage")
+	}
+
+	return f.GetGasMeterConsumptionPageFunc(ctx, mprn, serialNo, options, page)
+}
+
+// ListProducts implements octopusenergyapi.API
+func (f *Fake) ListProducts() ([]octopusenergyapi.Product, error) {
+	f.record("ListProducts")
+	return f.listProducts(context.Background(), octopusenergyapi.ListProductsOption{})
+}
+
+// ListProductsContext implements octopusenergyapi.API
+func (f *Fake) ListProductsContext(ctx context.Context) ([]octopusenergyapi.Product, error) {
+	f.record("ListProductsContext")
+	return f.listProducts(ctx, octopusenergyapi.ListProductsOption{})
+}
+
+// ListProductsWithOptions implements octopusenergyapi.API
+func (f *Fake) ListProductsWithOptions(options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+	f.record("ListProductsWithOptions", options)
+	return f.listProducts(context.Background(), options)
+}
+
+// ListProductsWithOptionsContext implements octopusenergyapi.API
+func (f *Fake) ListProductsWithOptionsContext(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+	f.record("ListProductsWithOptionsContext", options)
+	return f.listProducts(ctx, options)
+}
+
+// ProductsSeq implements octopusenergyapi.API
+func (f *Fake) ProductsSeq(ctx context.Context, options octopusenergyapi.ListProductsOption) func(yield func(octopusenergyapi.Product, error) bool) {
+	return lazySeq(func() ([]octopusenergyapi.Product, error) {
+		f.record("ProductsSeq", options)
+		return f.listProducts(ctx, options)
+	})
+}
+
+func (f *Fake) listProducts(ctx context.Context, options octopusenergyapi.ListProductsOption) ([]octopusenergyapi.Product, error) {
+	if f.ListProductsFunc == nil {
+		return nil, notProgrammed("ListProducts")
+	}
+
+	return f.ListProductsFunc(ctx, options)
+}
+
+// GetProduct implements octopusenergyapi.API
+func (f *Fake) GetProduct(productCode string) (octopusenergyapi.Product, error) {
+	f.record("GetProduct", productCode)
+	return f.getProduct(context.Background(), productCode, octopusenergyapi.ProductOption{})
+}
+
+// GetProductContext implements octopusenergyapi.API
+func (f *Fake) GetProductContext(ctx context.Context, productCode string) (octopusenergyapi.Product, error) {
+	f.record("GetProductContext", productCode)
+	return f.getProduct(ctx, productCode, octopusenergyapi.ProductOption{})
+}
+
+// GetProductWithOptions implements octopusenergyapi.API
+func (f *Fake) GetProductWithOptions(productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+	f.record("GetProductWithOptions", productCode, options)
+	return f.getProduct(context.Background(), productCode, options)
+}
+
+// GetProductWithOptionsContext implements octopusenergyapi.API
+func (f *Fake) GetProductWithOptionsContext(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+	f.record("GetProductWithOptionsContext", productCode, options)
+	return f.getProduct(ctx, productCode, options)
+}
+
+func (f *Fake) getProduct(ctx context.Context, productCode string, options octopusenergyapi.ProductOption) (octopusenergyapi.Product, error) {
+	if f.GetProductFunc == nil {
+		return octopusenergyapi.Product{}, notProgrammed("GetProduct")
+	}
+
+	return f.GetProductFunc(ctx, productCode, options)
+}
+
+// rateFunc is the function type of programmed tariff charges
+type rateFunc = func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error)
+
+// rates calls fn, if programmed
+func rates(ctx context.Context, fn rateFunc, name string, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	if fn == nil {
+		return nil, notProgrammed(name)
+	}
+
+	return fn(ctx, productCode, tariffCode, options)
+}
+
+// rateSeq returns an iterator over tariff charges returned by fn, most
+// recent first like the Seq methods of octopusenergyapi.Client
+func rateSeq(fn func() ([]octopusenergyapi.Rate, error)) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return lazySeq(func() ([]octopusenergyapi.Rate, error) {
+		items, err := fn()
+
+		sorted := make([]octopusenergyapi.Rate, len(items))
+		copy(sorted, items)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].ValidFrom.After(sorted[j].ValidFrom)
+		})
+
+		return sorted, err
+	})
+}
+
+// GetElecStandardUnitRates implements octopusenergyapi.API
+func (f *Fake) GetElecStandardUnitRates(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecStandardUnitRates", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetElecStandardUnitRatesFunc, "GetElecStandardUnitRates", productCode, tariffCode, options)
+}
+
+// GetElecStandardUnitRatesContext implements octopusenergyapi.API
+func (f *Fake) GetElecStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecStandardUnitRatesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetElecStandardUnitRatesFunc, "GetElecStandardUnitRates", productCode, tariffCode, options)
+}
+
+// ElecStandardUnitRatesSeq implements octopusenergyapi.API
+func (f *Fake) ElecStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("ElecStandardUnitRatesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetElecStandardUnitRatesFunc, "GetElecStandardUnitRates", productCode, tariffCode, options)
+	})
+}
+
+// GetGasStandardUnitRates implements octopusenergyapi.API
+func (f *Fake) GetGasStandardUnitRates(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetGasStandardUnitRates", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetGasStandardUnitRatesFunc, "GetGasStandardUnitRates", productCode, tariffCode, options)
+}
+
+// GetGasStandardUnitRatesContext implements octopusenergyapi.API
+func (f *Fake) GetGasStandardUnitRatesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetGasStandardUnitRatesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetGasStandardUnitRatesFunc, "GetGasStandardUnitRates", productCode, tariffCode, options)
+}
+
+// GasStandardUnitRatesSeq implements octopusenergyapi.API
+func (f *Fake) GasStandardUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("GasStandardUnitRatesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetGasStandardUnitRatesFunc, "GetGasStandardUnitRates", productCode, tariffCode, options)
+	})
+}
+
+// GetElecDayUnitRates implements octopusenergyapi.API
+func (f *Fake) GetElecDayUnitRates(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecDayUnitRates", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetElecDayUnitRatesFunc, "GetElecDayUnitRates", productCode, tariffCode, options)
+}
+
+// GetElecDayUnitRatesContext implements octopusenergyapi.API
+func (f *Fake) GetElecDayUnitRatesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecDayUnitRatesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetElecDayUnitRatesFunc, "GetElecDayUnitRates", productCode, tariffCode, options)
+}
+
+// ElecDayUnitRatesSeq implements octopusenergyapi.API
+func (f *Fake) ElecDayUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("ElecDayUnitRatesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetElecDayUnitRatesFunc, "GetElecDayUnitRates", productCode, tariffCode, options)
+	})
+}
+
+// GetElecNightUnitRates implements octopusenergyapi.API
+func (f *Fake) GetElecNightUnitRates(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecNightUnitRates", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetElecNightUnitRatesFunc, "GetElecNightUnitRates", productCode, tariffCode, options)
+}
+
+// GetElecNightUnitRatesContext implements octopusenergyapi.API
+func (f *Fake) GetElecNightUnitRatesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecNightUnitRatesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetElecNightUnitRatesFunc, "GetElecNightUnitRates", productCode, tariffCode, options)
+}
+
+// ElecNightUnitRatesSeq implements octopusenergyapi.API
+func (f *Fake) ElecNightUnitRatesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("ElecNightUnitRatesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetElecNightUnitRatesFunc, "GetElecNightUnitRates", productCode, tariffCode, options)
+	})
+}
+
+// GetElecStandingCharges implements octopusenergyapi.API
+func (f *Fake) GetElecStandingCharges(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecStandingCharges", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetElecStandingChargesFunc, "GetElecStandingCharges", productCode, tariffCode, options)
+}
+
+// GetElecStandingChargesContext implements octopusenergyapi.API
+func (f *Fake) GetElecStandingChargesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetElecStandingChargesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetElecStandingChargesFunc, "GetElecStandingCharges", productCode, tariffCode, options)
+}
+
+// ElecStandingChargesSeq implements octopusenergyapi.API
+func (f *Fake) ElecStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("ElecStandingChargesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetElecStandingChargesFunc, "GetElecStandingCharges", productCode, tariffCode, options)
+	})
+}
+
+// GetGasStandingCharges implements octopusenergyapi.API
+func (f *Fake) GetGasStandingCharges(productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetGasStandingCharges", productCode, tariffCode, options)
+	return rates(context.Background(), f.GetGasStandingChargesFunc, "GetGasStandingCharges", productCode, tariffCode, options)
+}
+
+// GetGasStandingChargesContext implements octopusenergyapi.API
+func (f *Fake) GetGasStandingChargesContext(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+	f.record("GetGasStandingChargesContext", productCode, tariffCode, options)
+	return rates(ctx, f.GetGasStandingChargesFunc, "GetGasStandingCharges", productCode, tariffCode, options)
+}
+
+// GasStandingChargesSeq implements octopusenergyapi.API
+func (f *Fake) GasStandingChargesSeq(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) func(yield func(octopusenergyapi.Rate, error) bool) {
+	return rateSeq(func() ([]octopusenergyapi.Rate, error) {
+		f.record("GasStandingChargesSeq", productCode, tariffCode, options)
+		return rates(ctx, f.GetGasStandingChargesFunc, "GetGasStandingCharges", productCode, tariffCode, options)
+	})
+}
+
+// GetAccount implements octopusenergyapi.API
+func (f *Fake) GetAccount(accountNumber string) (octopusenergyapi.Account, error) {
+	f.record("GetAccount", accountNumber)
+	return f.getAccount(context.Background(), accountNumber)
+}
+
+// GetAccountContext implements octopusenergyapi.API
+func (f *Fake) GetAccountContext(ctx context.Context, accountNumber string) (octopusenergyapi.Account, error) {
+	f.record("GetAccountContext", accountNumber)
+	return f.getAccount(ctx, accountNumber)
+}
+
+func (f *Fake) getAccount(ctx context.Context, accountNumber string) (octopusenergyapi.Account, error) {
+	if f.GetAccountFunc == nil {
+		return octopusenergyapi.Account{}, notProgrammed("GetAccount")
+	}
+
+	return f.GetAccountFunc(ctx, accountNumber)
+}
@@ -0,0 +1,116 @@
+package octopustest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// meterPointGSP is an example of code under test, depending on the API interface
+func meterPointGSP(api octopusenergyapi.API, mpan string) (string, error) {
+	mp, err := api.GetMeterPoint(mpan)
+	if err != nil {
+		return "", err
+	}
+
+	return mp.GSP.GSPGroupID, nil
+}
+
+func TestFake(t *testing.T) {
+	fake := &Fake{
+		GetMeterPointFunc: func(ctx context.Context, mpan string) (octopusenergyapi.MeterPoint, error) {
+			return octopusenergyapi.MeterPoint{MPAN: mpan, GSP: octopusenergyapi.GSPs[0]}, nil
+		},
+	}
+
+	gsp, err := meterPointGSP(fake, "0123456789")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "_A", gsp)
+	}
+
+	_, err = fake.GetAccountContext(context.Background(), "A-1234ABCD")
+	assert.True(t, errors.Is(err, ErrNotProgrammed))
+
+	assert.Equal(t, []Call{
+		{Method: "GetMeterPoint", Args: []interface{}{"0123456789"}},
+		{Method: "GetAccountContext", Args: []interface{}{"A-1234ABCD"}},
+	}, fake.Calls())
+	assert.Len(t, fake.CallsTo("GetMeterPoint"), 1)
+
+	fake.Reset()
+	assert.Empty(t, fake.Calls())
+}
+
+func TestFakeSeq(t *testing.T) {
+	// Programmed in ascending order, like Client.GetElecStandardUnitRates
+	start := time.Date(2020, 11, 28, 0, 0, 0, 0, time.UTC)
+	rates := []octopusenergyapi.Rate{
+		{ValueExcVAT: 1, ValidFrom: start},
+		{ValueExcVAT: 2, ValidFrom: start.Add(30 * time.Minute)},
+		{ValueExcVAT: 3, ValidFrom: start.Add(time.Hour)},
+	}
+	fake := &Fake{
+		GetElecStandardUnitRatesFunc: func(ctx context.Context, productCode, tariffCode string, options octopusenergyapi.RateOption) ([]octopusenergyapi.Rate, error) {
+			return rates, nil
+		},
+	}
+
+	var values []float32
+	fake.ElecStandardUnitRatesSeq(context.Background(), "AGILE-18-02-21", "E-1R-AGILE-18-02-21-C", octopusenergyapi.RateOption{})(func(r octopusenergyapi.Rate, err error) bool {
+		assert.Nil(t, err)
+		values = append(values, r.ValueExcVAT)
+		return len(values) < 2
+	})
+	assert.Equal(t, []float32{3, 2}, values)
+	assert.Equal(t, float32(1), rates[0].ValueExcVAT)
+
+	calls := fake.CallsTo("ElecStandardUnitRatesSeq")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "E-1R-AGILE-18-02-21-C", calls[0].Args[1])
+	}
+
+	// Nothing is called until iteration starts
+	fake.Reset()
+	seq := fake.ElecStandardUnitRatesSeq(context.Background(), "AGILE-18-02-21", "E-1R-AGILE-18-02-21-C", octopusenergyapi.RateOption{})
+	assert.Empty(t, fake.Calls())
+	seq(func(r octopusenergyapi.Rate, err error) bool { return false })
+	assert.Len(t, fake.CallsTo("ElecStandardUnitRatesSeq"), 1)
+
+	// Results are yielded before an error
+	fake.GetGasMeterConsumptionFunc = func(ctx context.Context, mprn, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+		return []octopusenergyapi.Consumption{{Value: 1}}, errors.New("page 2 failed")
+	}
+	var consumption []float32
+	var seqErr error
+	fake.GasMeterConsumptionSeq(context.Background(), "1234567890", "G4A01234567890", octopusenergyapi.ConsumptionOption{})(func(c octopusenergyapi.Consumption, err error) bool {
+		if err != nil {
+			seqErr = err
+			return false
+		}
+		consumption = append(consumption, c.Value)
+		return true
+	})
+	assert.Equal(t, []float32{1}, consumption)
+	assert.NotNil(t, seqErr)
+
+	fake.GetElecMeterConsumptionFunc = func(ctx context.Context, mpan, serialNo string, options octopusenergyapi.ConsumptionOption) ([]octopusenergyapi.Consumption, error) {
+		return []octopusenergyapi.Consumption{{Value: 1}, {Value: 2}}, nil
+	}
+	page, err := fake.GetElecMeterConsumptionAll("0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, page.Count)
+		assert.Len(t, page.Results, 2)
+	}
+
+	var errs int
+	fake.ProductsSeq(context.Background(), octopusenergyapi.ListProductsOption{})(func(p octopusenergyapi.Product, err error) bool {
+		assert.True(t, errors.Is(err, ErrNotProgrammed))
+		errs++
+		return true
+	})
+	assert.Equal(t, 1, errs)
+}
@@ -0,0 +1,299 @@
+package octopustest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mode selects whether a Recorder records or replays responses
+type Mode int
+
+const (
+	// ModeReplay serves recorded responses, without accessing the network
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the API and records responses
+	ModeRecord
+)
+
+// indexFile lists recorded interactions in a recording directory
+const indexFile = "index.json"
+
+// redacted replaces the API key in recordings
+const redacted = "REDACTED"
+
+var (
+	// meterPointRegex matches MPANs and MPRNs in request paths
+	meterPointRegex = regexp.MustCompile(`(electricity|gas)-meter-points/([0-9]+)`)
+	// meterPointBodyRegex matches MPANs and MPRNs in response bodies
+	meterPointBodyRegex = regexp.MustCompile(`"(mpan|mprn)"\s*:\s*"([0-9]+)"`)
+)
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	// File is the name of the file containing the response body,
+	// in the same format as returned by the API
+	File string `json:"file"`
+}
+
+// Recorder is an http.RoundTripper which records API responses into
+// a directory and replays them later, e.g. to run integration tests offline.
+//
+// The API key and meter point numbers (MPANs and MPRNs) are redacted from
+// recordings. Meter point numbers are replaced with stable fake numbers
+// of the same length, so requests made with real numbers are still matched
+// when replaying.
+type Recorder struct {
+	// Transport is used to send requests when recording.
+	// Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	dir  string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions map[string]Interaction
+}
+
+// NewRecorder returns a recorder using dir for recordings. When replaying,
+// recordings are loaded from dir. When recording, dir is created if needed
+// and recordings are saved when Save is called.
+func NewRecorder(dir string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		dir:          dir,
+		mode:         mode,
+		interactions: make(map[string]Interaction),
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(filepath.Join(dir, indexFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read recordings")
+		}
+
+		var interactions []Interaction
+		if err := json.Unmarshal(data, &interactions); err != nil {
+			return nil, errors.Wrap(err, "unable to decode recordings")
+		}
+
+		for _, i := range interactions {
+			r.interactions[interactionKey(i.Method, i.URL)] = i
+		}
+	}
+
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder, to be passed to
+// octopusenergyapi.NewClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+
+	return r.replay(req)
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	i, ok := r.interactions[interactionKey(req.Method, requestURI(req, ""))]
+	if !ok {
+		// Request may have been made with real meter point numbers
+		i, ok = r.interactions[interactionKey(req.Method, redactRequest(req, ""))]
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, errors.Errorf("no recorded response for %s %s", req.Method, req.URL.Path)
+	}
+
+	body, err := os.ReadFile(filepath.Join(r.dir, i.File))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read recorded response")
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(i.StatusCode),
+		StatusCode:    i.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read response")
+	}
+	// Caller receives the original response
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	apiKey, _, _ := req.BasicAuth()
+	i := Interaction{
+		Method:     req.Method,
+		URL:        redactRequest(req, apiKey),
+		StatusCode: resp.StatusCode,
+	}
+	i.File = fileName(i.URL)
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "unable to create recordings directory")
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, i.File), redactBody(body, apiKey), 0o644); err != nil {
+		return nil, errors.Wrap(err, "unable to write recorded response")
+	}
+
+	r.mu.Lock()
+	r.interactions[interactionKey(i.Method, i.URL)] = i
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Save writes the index of recorded interactions. It should be called
+// once all requests have been made.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	interactions := make([]Interaction, 0, len(r.interactions))
+	for _, i := range r.interactions {
+		interactions = append(interactions, i)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(interactions, func(a, b int) bool {
+		return interactions[a].File < interactions[b].File
+	})
+
+	data, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to encode recordings")
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return errors.Wrap(err, "unable to create recordings directory")
+	}
+
+	return errors.Wrap(os.WriteFile(filepath.Join(r.dir, indexFile), data, 0o644), "unable to write recordings")
+}
+
+func interactionKey(method, uri string) string {
+	return method + " " + uri
+}
+
+// requestURI returns path and query of a request, relative to the API
+// version, with apiKey replaced
+func requestURI(req *http.Request, apiKey string) string {
+	uri := req.URL.Path
+	if i := strings.Index(uri, "/v1/"); i >= 0 {
+		uri = uri[i+len("/v1/"):]
+	}
+	uri = strings.TrimPrefix(uri, "/")
+
+	if req.URL.RawQuery != "" {
+		uri += "?" + req.URL.RawQuery
+	}
+
+	if apiKey != "" {
+		uri = strings.ReplaceAll(uri, apiKey, redacted)
+	}
+
+	return uri
+}
+
+// redactRequest returns request URI with meter point numbers redacted
+func redactRequest(req *http.Request, apiKey string) string {
+	return redactPaths(requestURI(req, apiKey))
+}
+
+// redactPaths replaces meter point numbers in URL path segments
+func redactPaths(s string) string {
+	return meterPointRegex.ReplaceAllStringFunc(s, func(match string) string {
+		m := meterPointRegex.FindStringSubmatch(match)
+		return m[1] + "-meter-points/" + fakeNumber(m[2])
+	})
+}
+
+// redactBody replaces the API key and meter point numbers in a response body,
+// including in links to other pages. Numbers are only replaced in "mpan" and
+// "mprn" values and in meter point paths, so unrelated digits are kept.
+func redactBody(body []byte, apiKey string) []byte {
+	s := string(body)
+	if apiKey != "" {
+		s = strings.ReplaceAll(s, apiKey, redacted)
+	}
+
+	s = meterPointBodyRegex.ReplaceAllStringFunc(s, func(match string) string {
+		m := meterPointBodyRegex.FindStringSubmatchIndex(match)
+		return match[:m[4]] + fakeNumber(match[m[4]:m[5]]) + match[m[5]:]
+	})
+
+	return []byte(redactPaths(s))
+}
+
+// fakeNumber deterministically derives a fake number of the same length
+func fakeNumber(number string) string {
+	sum := sha256.Sum256([]byte(number))
+
+	fake := make([]byte, len(number))
+	for i := range fake {
+		fake[i] = '0' + sum[i%len(sum)]%10
+	}
+
+	return string(fake)
+}
+
+// fileName derives a file name from a redacted request URI,
+// e.g. products_AGILE-18-02-21.json
+func fileName(uri string) string {
+	path, query, _ := strings.Cut(uri, "?")
+
+	name := strings.ReplaceAll(strings.Trim(path, "/"), "/", "_")
+	if name == "" {
+		name = "root"
+	}
+	if query != "" {
+		sum := sha256.Sum256([]byte(query))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+
+	return name + ".json"
+}
@@ -0,0 +1,120 @@
+package octopustest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+
+	server := NewServer()
+	defer server.Close()
+
+	// Record responses from the fake server
+	recorder, err := NewRecorder(dir, ModeRecord)
+	if !assert.Nil(t, err) {
+		return
+	}
+	recorder.Transport = server.Client().Transport
+
+	client, err := octopusenergyapi.NewClient(APIKey, recorder.Client(), octopusenergyapi.WithBaseURL(server.BaseURL()))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	mp, err := client.GetMeterPoint("0123456789012")
+	if assert.Nil(t, err) {
+		// Caller receives unredacted response
+		assert.Equal(t, "0123456789012", mp.MPAN)
+	}
+
+	consumption, err := client.GetElecMeterConsumption("0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{PageSize: 100})
+	if assert.Nil(t, err) {
+		assert.Len(t, consumption, 144)
+	}
+
+	_, err = client.GetProduct("UNKNOWN")
+	assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+
+	assert.Nil(t, recorder.Save())
+
+	// Recordings don't contain the API key or MPAN
+	files, err := os.ReadDir(dir)
+	if assert.Nil(t, err) {
+		assert.Len(t, files, 5)
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+			if assert.Nil(t, err) {
+				assert.NotContains(t, string(data), APIKey)
+				assert.NotContains(t, string(data), "0123456789012")
+			}
+			assert.False(t, strings.Contains(f.Name(), "0123456789012"))
+		}
+	}
+
+	// Replay without a server
+	server.Close()
+
+	replayer, err := NewRecorder(dir, ModeReplay)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	client, err = octopusenergyapi.NewClient("anotherkey", replayer.Client(), octopusenergyapi.WithBaseURL("https://example.com/v1"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	mp, err = client.GetMeterPoint("0123456789012")
+	if assert.Nil(t, err) {
+		assert.Equal(t, fakeNumber("0123456789012"), mp.MPAN)
+		assert.Equal(t, "_C", mp.GSP.GSPGroupID)
+	}
+
+	// Redacted MPAN can be used as well
+	_, err = client.GetMeterPoint(mp.MPAN)
+	assert.Nil(t, err)
+
+	replayed, err := client.GetElecMeterConsumption("0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{PageSize: 100})
+	if assert.Nil(t, err) {
+		assert.Equal(t, consumption, replayed)
+	}
+
+	_, err = client.GetProduct("UNKNOWN")
+	assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+
+	_, err = client.GetProduct("AGILE-18-02-21")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no recorded response")
+	}
+}
+
+func TestNewRecorderMissing(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing"), ModeReplay)
+	assert.NotNil(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	// The MPRN also appears within a consumption value and a serial number
+	body := `{"mprn": "123456", "serial_number": "G4A1234567", "consumption": 0.123456,` +
+		`"next": "https://api.octopus.energy/v1/gas-meter-points/123456/meters/G4A1234567/consumption/?page=2"}`
+
+	fake := fakeNumber("123456")
+	assert.Equal(t, `{"mprn": "`+fake+`", "serial_number": "G4A1234567", "consumption": 0.123456,`+
+		`"next": "https://api.octopus.energy/v1/gas-meter-points/`+fake+`/meters/G4A1234567/consumption/?page=2"}`,
+		string(redactBody([]byte(body), "")))
+}
+
+func TestFakeNumber(t *testing.T) {
+	fake := fakeNumber("0123456789012")
+	assert.Len(t, fake, 13)
+	assert.NotEqual(t, "0123456789012", fake)
+	assert.Equal(t, fake, fakeNumber("0123456789012"))
+}
@@ -0,0 +1,680 @@
+// Package octopustest provides an in-process fake of the Octopus Energy API
+// for testing code using octopusenergyapi.Client.
+package octopustest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// APIKey is the API key accepted by a Server, unless changed
+const APIKey = "sk_test_octopustest"
+
+// Fuel types and tariff charges, as used in API paths
+const (
+	FuelElectricity = "electricity"
+	FuelGas         = "gas"
+
+	ChargeStandardUnitRates = "standard-unit-rates"
+	ChargeStandingCharges   = "standing-charges"
+	ChargeDayUnitRates      = "day-unit-rates"
+	ChargeNightUnitRates    = "night-unit-rates"
+)
+
+const (
+	defaultPageSize        = 100
+	maxRatesPageSize       = 1500
+	maxConsumptionPageSize = 25000
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Server is a fake Octopus Energy API, serving data from fixtures.
+// It supports products, tariff charges, meter points, grid supply points,
+// consumption and accounts, including pagination and query filters.
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the API key requests have to be authenticated with
+	APIKey string
+
+	mu             sync.RWMutex
+	products       map[string]octopusenergyapi.Product
+	charges        map[string][]octopusenergyapi.Rate
+	meterPoints    map[string]meterPointJSON
+	gasMeterPoints map[string]gasMeterPointJSON
+	postcodes      map[string]string
+	consumption    map[string][]octopusenergyapi.Consumption
+	accounts       map[string]octopusenergyapi.Account
+}
+
+// NewServer starts a server seeded with the default fixtures.
+// The server should be closed when no longer needed.
+func NewServer() *Server {
+	s := NewEmptyServer()
+
+	fixtures, err := fs.Sub(defaultFixtures, "fixtures")
+	if err == nil {
+		err = s.Load(fixtures)
+	}
+	if err != nil {
+		// Embedded fixtures are always valid
+		panic(err)
+	}
+
+	return s
+}
+
+// NewEmptyServer starts a server without any data
+func NewEmptyServer() *Server {
+	s := &Server{
+		APIKey:         APIKey,
+		products:       make(map[string]octopusenergyapi.Product),
+		charges:        make(map[string][]octopusenergyapi.Rate),
+		meterPoints:    make(map[string]meterPointJSON),
+		gasMeterPoints: make(map[string]gasMeterPointJSON),
+		postcodes:      make(map[string]string),
+		consumption:    make(map[string][]octopusenergyapi.Consumption),
+		accounts:       make(map[string]octopusenergyapi.Account),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// APIClient returns an API client configured to use the server.
+// Client of the embedded httptest.Server returns a plain HTTP client.
+func (s *Server) APIClient(options ...octopusenergyapi.Option) (*octopusenergyapi.Client, error) {
+	options = append([]octopusenergyapi.Option{octopusenergyapi.WithBaseURL(s.BaseURL())}, options...)
+
+	return octopusenergyapi.NewClient(s.APIKey, s.Client(), options...)
+}
+
+// BaseURL returns the base URL of the fake API
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// AddProduct adds a product, replacing one with the same code
+func (s *Server) AddProduct(product octopusenergyapi.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products[product.Code] = product
+}
+
+// SetCharges sets a charge (e.g. ChargeStandardUnitRates) of a tariff
+func (s *Server) SetCharges(fuel, productCode, tariffCode, charge string, rates []octopusenergyapi.Rate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.charges[chargesKey(fuel, productCode, tariffCode, charge)] = append([]octopusenergyapi.Rate(nil), rates...)
+}
+
+// AddMeterPoint adds an electricity meter point
+func (s *Server) AddMeterPoint(mpan, gspGroupID string, profileClass int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.meterPoints[mpan] = meterPointJSON{GSP: gspGroupID, MPAN: mpan, ProfileClass: profileClass}
+}
+
+// AddGasMeterPoint adds a gas meter point with its estimated annual
+// consumption and meters
+func (s *Server) AddGasMeterPoint(mprn string, consumptionStandard int, meters ...octopusenergyapi.Meter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gasMeterPoints[mprn] = gasMeterPointJSON{MPRN: mprn, ConsumptionStandard: consumptionStandard, Meters: meters}
+}
+
+// AddPostcode sets the grid supply point group of a postcode
+func (s *Server) AddPostcode(postcode, gspGroupID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.postcodes[normalisePostcode(postcode)] = gspGroupID
+}
+
+// SetConsumption sets consumption of a meter, identified by MPAN or MPRN
+// and serial number
+func (s *Server) SetConsumption(fuel, meterPoint, serialNo string, consumption []octopusenergyapi.Consumption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consumption[consumptionKey(fuel, meterPoint, serialNo)] = append([]octopusenergyapi.Consumption(nil), consumption...)
+}
+
+// AddAccount adds an account, replacing one with the same number
+func (s *Server) AddAccount(account octopusenergyapi.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accounts[account.Number] = account
+}
+
+// Load adds data from fixture files in fsys. All files are optional:
+//
+//	products.json     - array of products, as returned by the product endpoint
+//	charges.json      - array of {"fuel", "product_code", "tariff_code", "charge", "results"}
+//	meter_points.json - {"electricity": [{"mpan", "gsp", "profile_class"}], "gas": [{"mprn", "consumption_standard", "meters"}]}
+//	postcodes.json    - object mapping postcodes to grid supply point groups
+//	consumption.json  - array of {"fuel", "meter_point", "serial_number", "results"}
+//	accounts.json     - array of accounts, as returned by the account endpoint
+func (s *Server) Load(fsys fs.FS) error {
+	var products []octopusenergyapi.Product
+	if err := loadFixture(fsys, "products.json", &products); err != nil {
+		return err
+	}
+	for _, product := range products {
+		s.AddProduct(product)
+	}
+
+	var charges []chargesFixture
+	if err := loadFixture(fsys, "charges.json", &charges); err != nil {
+		return err
+	}
+	for _, c := range charges {
+		s.SetCharges(c.Fuel, c.ProductCode, c.TariffCode, c.Charge, c.Results)
+	}
+
+	var meterPoints struct {
+		Electricity []meterPointJSON    `json:"electricity"`
+		Gas         []gasMeterPointJSON `json:"gas"`
+	}
+	if err := loadFixture(fsys, "meter_points.json", &meterPoints); err != nil {
+		return err
+	}
+	for _, mp := range meterPoints.Electricity {
+		s.AddMeterPoint(mp.MPAN, mp.GSP, mp.ProfileClass)
+	}
+	for _, mp := range meterPoints.Gas {
+		s.AddGasMeterPoint(mp.MPRN, mp.ConsumptionStandard, mp.Meters...)
+	}
+
+	var postcodes map[string]string
+	if err := loadFixture(fsys, "postcodes.json", &postcodes); err != nil {
+		return err
+	}
+	for postcode, gsp := range postcodes {
+		s.AddPostcode(postcode, gsp)
+	}
+
+	var consumption []consumptionFixture
+	if err := loadFixture(fsys, "consumption.json", &consumption); err != nil {
+		return err
+	}
+	for _, c := range consumption {
+		s.SetConsumption(c.Fuel, c.MeterPoint, c.SerialNumber, c.Results)
+	}
+
+	var accounts []octopusenergyapi.Account
+	if err := loadFixture(fsys, "accounts.json", &accounts); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		s.AddAccount(account)
+	}
+
+	return nil
+}
+
+// loadFixture decodes a fixture file, if it exists
+func loadFixture(fsys fs.FS, name string, v interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to read %s", name)
+	}
+
+	return errors.Wrapf(json.Unmarshal(data, v), "unable to decode %s", name)
+}
+
+type meterPointJSON struct {
+	GSP          string `json:"gsp"`
+	MPAN         string `json:"mpan"`
+	ProfileClass int    `json:"profile_class"`
+}
+
+type gasMeterPointJSON struct {
+	MPRN                string                   `json:"mprn"`
+	ConsumptionStandard int                      `json:"consumption_standard"`
+	Meters              []octopusenergyapi.Meter `json:"meters"`
+}
+
+type chargesFixture struct {
+	Fuel        string                  `json:"fuel"`
+	ProductCode string                  `json:"product_code"`
+	TariffCode  string                  `json:"tariff_code"`
+	Charge      string                  `json:"charge"`
+	Results     []octopusenergyapi.Rate `json:"results"`
+}
+
+type consumptionFixture struct {
+	Fuel         string                         `json:"fuel"`
+	MeterPoint   string                         `json:"meter_point"`
+	SerialNumber string                         `json:"serial_number"`
+	Results      []octopusenergyapi.Consumption `json:"results"`
+}
+
+type gspJSON struct {
+	GroupID string `json:"group_id"`
+}
+
+func chargesKey(fuel, productCode, tariffCode, charge string) string {
+	return strings.Join([]string{fuel, productCode, tariffCode, charge}, "/")
+}
+
+func consumptionKey(fuel, meterPoint, serialNo string) string {
+	return strings.Join([]string{fuel, meterPoint, serialNo}, "/")
+}
+
+func normalisePostcode(postcode string) string {
+	return strings.ToUpper(strings.ReplaceAll(postcode, " ", ""))
+}
+
+// serveHTTP routes requests to the fake endpoints
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+		return
+	}
+
+	if username, _, ok := r.BasicAuth(); !ok || username != s.APIKey {
+		writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided.")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == r.URL.Path || !strings.HasSuffix(path, "/") {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case len(parts) == 1 && parts[0] == "products":
+		s.listProducts(w, r)
+	case len(parts) == 2 && parts[0] == "products":
+		s.getProduct(w, parts[1])
+	case len(parts) == 5 && parts[0] == "products" && strings.HasSuffix(parts[2], "-tariffs"):
+		s.listCharges(w, r, strings.TrimSuffix(parts[2], "-tariffs"), parts[1], parts[3], parts[4])
+	case len(parts) == 2 && parts[0] == "electricity-meter-points":
+		s.getMeterPoint(w, parts[1])
+	case len(parts) == 2 && parts[0] == "gas-meter-points":
+		s.getGasMeterPoint(w, parts[1])
+	case len(parts) == 5 && strings.HasSuffix(parts[0], "-meter-points") && parts[2] == "meters" && parts[4] == "consumption":
+		s.listConsumption(w, r, strings.TrimSuffix(parts[0], "-meter-points"), parts[1], parts[3])
+	case len(parts) == 2 && parts[0] == "industry" && parts[1] == "grid-supply-points":
+		s.listGridSupplyPoints(w, r)
+	case len(parts) == 2 && parts[0] == "accounts":
+		s.getAccount(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (s *Server) listProducts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	availableAt, err := parseTimeParam(q, "available_at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var products []octopusenergyapi.Product
+	for _, p := range s.products {
+		if !matchBool(q, "is_variable", p.IsVariable) || !matchBool(q, "is_green", p.IsGreen) ||
+			!matchBool(q, "is_tracker", p.IsTracker) || !matchBool(q, "is_prepay", p.IsPrepay) ||
+			!matchBool(q, "is_business", p.IsBusiness) {
+			continue
+		}
+
+		if brand := q.Get("brand"); brand != "" && brand != p.Brand {
+			continue
+		}
+
+		if !availableAt.IsZero() && (availableAt.Before(p.AvailableFrom) || (!p.AvailableTo.IsZero() && !availableAt.Before(p.AvailableTo))) {
+			continue
+		}
+
+		// Tariffs are only included when retrieving a single product
+		p.SingleRegisterElecTariffs = nil
+		p.DualRegisterElecTariffs = nil
+		p.SingleRegisterGasTariffs = nil
+		products = append(products, p)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Code < products[j].Code
+	})
+
+	writePage(w, r, products, defaultPageSize)
+}
+
+func (s *Server) getProduct(w http.ResponseWriter, code string) {
+	product, ok := s.products[code]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	writeJSON(w, product)
+}
+
+func (s *Server) listCharges(w http.ResponseWriter, r *http.Request, fuel, productCode, tariffCode, charge string) {
+	rates, ok := s.charges[chargesKey(fuel, productCode, tariffCode, charge)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	from, to, err := parsePeriod(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var results []octopusenergyapi.Rate
+	for _, rate := range rates {
+		if !to.IsZero() && !rate.ValidFrom.Before(to) {
+			continue
+		}
+		if !from.IsZero() && !rate.ValidTo.IsZero() && !rate.ValidTo.After(from) {
+			continue
+		}
+		results = append(results, rate)
+	}
+
+	// Most recent rates are returned first
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ValidFrom.After(results[j].ValidFrom)
+	})
+
+	writePage(w, r, results, maxRatesPageSize)
+}
+
+func (s *Server) getMeterPoint(w http.ResponseWriter, mpan string) {
+	mp, ok := s.meterPoints[mpan]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	writeJSON(w, mp)
+}
+
+func (s *Server) getGasMeterPoint(w http.ResponseWriter, mprn string) {
+	mp, ok := s.gasMeterPoints[mprn]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	writeJSON(w, mp)
+}
+
+func (s *Server) listConsumption(w http.ResponseWriter, r *http.Request, fuel, meterPoint, serialNo string) {
+	consumption, ok := s.consumption[consumptionKey(fuel, meterPoint, serialNo)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	q := r.URL.Query()
+	from, to, err := parsePeriod(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var results []octopusenergyapi.Consumption
+	for _, c := range consumption {
+		if !from.IsZero() && c.IntervalStart.Before(from) {
+			continue
+		}
+		if !to.IsZero() && c.IntervalEnd.After(to) {
+			continue
+		}
+		results = append(results, c)
+	}
+
+	if groupBy := q.Get("group_by"); groupBy != "" {
+		results, err = groupConsumption(results, groupBy)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	switch q.Get("order_by") {
+	case "", "-period":
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].IntervalStart.After(results[j].IntervalStart)
+		})
+	case "period":
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].IntervalStart.Before(results[j].IntervalStart)
+		})
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid order_by.")
+		return
+	}
+
+	writePage(w, r, results, maxConsumptionPageSize)
+}
+
+// groupConsumption aggregates consumption into intervals, in UTC
+func groupConsumption(consumption []octopusenergyapi.Consumption, groupBy string) ([]octopusenergyapi.Consumption, error) {
+	var start func(t time.Time) time.Time
+	var end func(t time.Time) time.Time
+
+	switch groupBy {
+	case "hour":
+		start = func(t time.Time) time.Time { return t.Truncate(time.Hour) }
+		end = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case "day":
+		start = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+		end = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case "week":
+		start = func(t time.Time) time.Time {
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		}
+		end = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case "month":
+		start = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC) }
+		end = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case "quarter":
+		start = func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+		}
+		end = func(t time.Time) time.Time { return t.AddDate(0, 3, 0) }
+	default:
+		return nil, errors.Errorf("Invalid group_by %s.", groupBy)
+	}
+
+	groups := make(map[time.Time]*octopusenergyapi.Consumption)
+	for _, c := range consumption {
+		s := start(c.IntervalStart.UTC())
+		g, ok := groups[s]
+		if !ok {
+			g = &octopusenergyapi.Consumption{IntervalStart: s, IntervalEnd: end(s)}
+			groups[s] = g
+		}
+		g.Value += c.Value
+	}
+
+	results := make([]octopusenergyapi.Consumption, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, *g)
+	}
+
+	return results, nil
+}
+
+func (s *Server) listGridSupplyPoints(w http.ResponseWriter, r *http.Request) {
+	var results []gspJSON
+
+	if postcode := r.URL.Query().Get("postcode"); postcode != "" {
+		if gsp, ok := s.postcodes[normalisePostcode(postcode)]; ok {
+			results = append(results, gspJSON{gsp})
+		}
+	} else {
+		for _, gsp := range octopusenergyapi.GSPs {
+			results = append(results, gspJSON{gsp.GSPGroupID})
+		}
+	}
+
+	writePage(w, r, results, defaultPageSize)
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, number string) {
+	account, ok := s.accounts[number]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	writeJSON(w, account)
+}
+
+// matchBool checks if a boolean filter is either not set or equal to value
+func matchBool(q url.Values, name string, value bool) bool {
+	param := q.Get(name)
+	if param == "" {
+		return true
+	}
+
+	b, err := strconv.ParseBool(param)
+	return err == nil && b == value
+}
+
+// parsePeriod parses period_from and period_to parameters
+func parsePeriod(q url.Values) (time.Time, time.Time, error) {
+	from, err := parseTimeParam(q, "period_from")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, err := parseTimeParam(q, "period_to")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to, nil
+}
+
+// parseTimeParam parses a time parameter in one of the formats accepted by the API
+func parseTimeParam(q url.Values, name string) (time.Time, error) {
+	value := q.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000-0700", "2006-01-02T15:04:05-0700", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.Errorf("Invalid %s.", name)
+}
+
+// writePage writes a page of results selected by page and page_size parameters
+func writePage[T any](w http.ResponseWriter, r *http.Request, results []T, maxPageSize int) {
+	q := r.URL.Query()
+
+	pageSize := defaultPageSize
+	if ps := q.Get("page_size"); ps != "" {
+		n, err := strconv.Atoi(ps)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "Invalid page_size.")
+			return
+		}
+		pageSize = n
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	page := 1
+	if p := q.Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || (n-1)*pageSize >= len(results) && n > 1 {
+			writeError(w, http.StatusNotFound, "Invalid page.")
+			return
+		}
+		page = n
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	if end > len(results) {
+		end = len(results)
+	}
+
+	data := octopusenergyapi.Page[T]{
+		Count:   len(results),
+		Results: results[start:end],
+	}
+	if data.Results == nil {
+		data.Results = []T{}
+	}
+
+	link := func(page int) string {
+		u := *r.URL
+		u.Scheme = "http"
+		u.Host = r.Host
+
+		q := u.Query()
+		q.Del("page")
+		if page > 1 {
+			q.Set("page", strconv.Itoa(page))
+		}
+		u.RawQuery = q.Encode()
+
+		return u.String()
+	}
+	if end < len(results) {
+		data.Next = link(page + 1)
+	}
+	if page > 1 {
+		data.Previous = link(page - 1)
+	}
+
+	writeJSON(w, data)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the same format as the API
+func writeError(w http.ResponseWriter, statusCode int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"detail": detail})
+}
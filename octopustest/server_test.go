@@ -0,0 +1,259 @@
+package octopustest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T) (*Server, *octopusenergyapi.Client) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := server.APIClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, client
+}
+
+func TestServerAuth(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client, err := octopusenergyapi.NewClient("wrongkey", server.Client(), octopusenergyapi.WithBaseURL(server.BaseURL()))
+	if assert.Nil(t, err) {
+		_, err = client.GetMeterPoint("0123456789")
+		assert.True(t, errors.Is(err, octopusenergyapi.ErrUnauthorized))
+	}
+}
+
+func TestServerMeterPoints(t *testing.T) {
+	_, client := newTestClient(t)
+
+	mp, err := client.GetMeterPoint("0123456789012")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "0123456789012", mp.MPAN)
+		assert.Equal(t, "_C", mp.GSP.GSPGroupID)
+	}
+
+	gmp, err := client.GetGasMeterPoint("1234567890")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1234567890", gmp.MPRN)
+		assert.Equal(t, 12000, gmp.ConsumptionStandard)
+		if assert.Len(t, gmp.Meters, 1) {
+			assert.Equal(t, "G4A01234567890", gmp.Meters[0].SerialNumber)
+		}
+	}
+
+	_, err = client.GetMeterPoint("9999999999")
+	assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+}
+
+func TestServerGridSupplyPoint(t *testing.T) {
+	server, client := newTestClient(t)
+
+	gsp, err := client.GetGridSupplyPoint("SW1A 1AA")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "_C", gsp.GSPGroupID)
+	}
+
+	_, err = client.GetGridSupplyPoint("ZE1 0AA")
+	assert.True(t, errors.Is(err, octopusenergyapi.ErrNoGridSupplyPoint))
+
+	server.AddPostcode("ze1 0aa", "_P")
+	gsp, err = client.GetGridSupplyPoint("ZE1 0AA")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "_P", gsp.GSPGroupID)
+	}
+}
+
+func TestServerProducts(t *testing.T) {
+	_, client := newTestClient(t)
+
+	products, err := client.ListProducts()
+	if assert.Nil(t, err) && assert.Len(t, products, 3) {
+		assert.Equal(t, "AGILE-18-02-21", products[0].Code)
+		assert.Nil(t, products[0].SingleRegisterElecTariffs)
+	}
+
+	products, err = client.ListProductsWithOptions(octopusenergyapi.ListProductsOption{IsGreen: octopusenergyapi.Bool(true)})
+	if assert.Nil(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "AGILE-18-02-21", products[0].Code)
+	}
+
+	products, err = client.ListProductsWithOptions(octopusenergyapi.ListProductsOption{
+		AvailableAt: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if assert.Nil(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "VAR-17-01-11", products[0].Code)
+	}
+
+	product, err := client.GetProduct("FIX-12M-20-09-21")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "E-1R-FIX-12M-20-09-21-A", product.SingleRegisterElecTariffs["_A"]["direct_debit_monthly"].Code)
+	}
+
+	_, err = client.GetProduct("UNKNOWN")
+	assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+}
+
+func TestServerRates(t *testing.T) {
+	_, client := newTestClient(t)
+
+	t.Run("paginated", func(t *testing.T) {
+		rates, err := client.GetElecStandardUnitRates("AGILE-18-02-21", "E-1R-AGILE-18-02-21-C", octopusenergyapi.RateOption{PageSize: 50})
+		if assert.Nil(t, err) && assert.Len(t, rates, 144) {
+			assert.Equal(t, time.Date(2020, 11, 26, 0, 0, 0, 0, time.UTC), rates[0].ValidFrom.UTC())
+			assert.Equal(t, time.Date(2020, 11, 28, 23, 30, 0, 0, time.UTC), rates[143].ValidFrom.UTC())
+		}
+	})
+
+	t.Run("period", func(t *testing.T) {
+		rates, err := client.GetElecStandardUnitRates("AGILE-18-02-21", "E-1R-AGILE-18-02-21-C", octopusenergyapi.RateOption{
+			From: time.Date(2020, 11, 27, 16, 0, 0, 0, time.UTC),
+			To:   time.Date(2020, 11, 27, 19, 0, 0, 0, time.UTC),
+		})
+		if assert.Nil(t, err) && assert.Len(t, rates, 6) {
+			assert.Equal(t, time.Date(2020, 11, 27, 16, 0, 0, 0, time.UTC), rates[0].ValidFrom.UTC())
+		}
+
+		rates, err = client.GetElecStandardUnitRates("VAR-17-01-11", "E-1R-VAR-17-01-11-A", octopusenergyapi.RateOption{
+			From: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if assert.Nil(t, err) && assert.Len(t, rates, 1) {
+			assert.Equal(t, float32(16), rates[0].ValueExcVAT)
+			assert.True(t, rates[0].ValidTo.IsZero())
+		}
+	})
+
+	t.Run("charges", func(t *testing.T) {
+		charges, err := client.GetGasStandingCharges("FIX-12M-20-09-21", "G-1R-FIX-12M-20-09-21-C", octopusenergyapi.RateOption{})
+		if assert.Nil(t, err) && assert.Len(t, charges, 1) {
+			assert.Equal(t, float32(17), charges[0].ValueExcVAT)
+			assert.Equal(t, "DIRECT_DEBIT", charges[0].PaymentMethod)
+		}
+
+		night, err := client.GetElecNightUnitRates("VAR-17-01-11", "E-2R-VAR-17-01-11-A", octopusenergyapi.RateOption{})
+		if assert.Nil(t, err) && assert.Len(t, night, 1) {
+			assert.Equal(t, float32(9.91), night[0].ValueExcVAT)
+		}
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := client.GetElecStandardUnitRates("AGILE-18-02-21", "E-1R-AGILE-18-02-21-Z", octopusenergyapi.RateOption{})
+		assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+	})
+}
+
+func TestServerConsumption(t *testing.T) {
+	_, client := newTestClient(t)
+
+	t.Run("all", func(t *testing.T) {
+		consumption, err := client.GetElecMeterConsumption("0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{})
+		if assert.Nil(t, err) && assert.Len(t, consumption, 144) {
+			// Most recent consumption first
+			assert.True(t, consumption[0].IntervalStart.After(consumption[1].IntervalStart))
+		}
+
+		// The full series over several pages holds the total count
+		options := octopusenergyapi.ConsumptionOption{PageSize: 50}
+		page, err := client.GetElecMeterConsumptionPage("0123456789012", "19L0123456", options, 1)
+		if assert.Nil(t, err) && assert.NotEmpty(t, page.Next) {
+			consumption, err = client.GetElecMeterConsumption("0123456789012", "19L0123456", options)
+			if assert.Nil(t, err) {
+				assert.Len(t, consumption, page.Count)
+			}
+		}
+	})
+
+	t.Run("page", func(t *testing.T) {
+		page, err := client.GetGasMeterConsumptionPage("1234567890", "G4A01234567890", octopusenergyapi.ConsumptionOption{
+			PageSize: 100,
+			OrderBy:  "period",
+		}, 2)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 144, page.Count)
+			assert.Len(t, page.Results, 44)
+			assert.Empty(t, page.Next)
+			assert.NotEmpty(t, page.Previous)
+			assert.Equal(t, time.Date(2020, 11, 28, 2, 0, 0, 0, time.UTC), page.Results[0].IntervalStart.UTC())
+		}
+
+		_, err = client.GetGasMeterConsumptionPage("1234567890", "G4A01234567890", octopusenergyapi.ConsumptionOption{}, 3)
+		assert.True(t, errors.Is(err, octopusenergyapi.ErrNotFound))
+	})
+
+	t.Run("group_by", func(t *testing.T) {
+		consumption, err := client.GetElecMeterConsumption("0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{
+			From:    time.Date(2020, 11, 27, 0, 0, 0, 0, time.UTC),
+			GroupBy: "day",
+			OrderBy: "period",
+		})
+		if assert.Nil(t, err) && assert.Len(t, consumption, 2) {
+			assert.Equal(t, time.Date(2020, 11, 27, 0, 0, 0, 0, time.UTC), consumption[0].IntervalStart.UTC())
+			assert.Equal(t, time.Date(2020, 11, 28, 0, 0, 0, 0, time.UTC), consumption[0].IntervalEnd.UTC())
+			assert.Greater(t, consumption[0].Value, float32(0))
+		}
+	})
+
+	t.Run("seq", func(t *testing.T) {
+		var n int
+		client.ElecMeterConsumptionSeq(context.Background(), "0123456789012", "19L0123456", octopusenergyapi.ConsumptionOption{PageSize: 10})(func(c octopusenergyapi.Consumption, err error) bool {
+			assert.Nil(t, err)
+			n++
+			return n < 15
+		})
+		assert.Equal(t, 15, n)
+	})
+}
+
+func TestServerAccount(t *testing.T) {
+	_, client := newTestClient(t)
+
+	account, err := client.GetAccount("A-1234ABCD")
+	if assert.Nil(t, err) && assert.Len(t, account.Properties, 1) {
+		mps := account.Properties[0].ElecMeterPoints
+		if assert.Len(t, mps, 1) {
+			assert.Equal(t, "0123456789012", mps[0].MPAN)
+		}
+	}
+}
+
+func TestServerLoad(t *testing.T) {
+	server := NewEmptyServer()
+	defer server.Close()
+
+	fsys := fstest.MapFS{
+		"meter_points.json": {Data: []byte(`{"electricity":[{"mpan":"1111111111","gsp":"_B","profile_class":2}]}`)},
+		"postcodes.json":    {Data: []byte(`{"CB1 1AA":"_B"}`)},
+	}
+	assert.Nil(t, server.Load(fsys))
+
+	client, err := server.APIClient()
+	if assert.Nil(t, err) {
+		mp, err := client.GetMeterPoint("1111111111")
+		if assert.Nil(t, err) {
+			assert.Equal(t, "_B", mp.GSP.GSPGroupID)
+			assert.Equal(t, 2, mp.ProfileClass)
+		}
+
+		gsp, err := client.GetGridSupplyPoint("cb1 1aa")
+		if assert.Nil(t, err) {
+			assert.Equal(t, "_B", gsp.GSPGroupID)
+		}
+
+		products, err := client.ListProducts()
+		assert.Nil(t, err)
+		assert.Empty(t, products)
+	}
+
+	err = server.Load(fstest.MapFS{"products.json": {Data: []byte(`{`)}})
+	assert.NotNil(t, err)
+}
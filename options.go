@@ -0,0 +1,83 @@
+package octopusenergyapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Option configures a Client
+type Option func(*Client) error
+
+// Logger is used by the Client to report retried requests.
+// It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithBaseURL sets the URL of the API, e.g. to use a proxy or a mock server.
+// Defaults to https://api.octopus.energy/v1
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return errors.Wrap(err, "unable to parse base url")
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return errors.Errorf("base url %s should be absolute", baseURL)
+		}
+
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests,
+// overriding the one passed to NewClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) error {
+		if httpClient == nil {
+			return errors.New("http client should not be nil")
+		}
+
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithUserAgent sets User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithRetry sets the retry policy of the client, which defaults to
+// DefaultRetryPolicy. Use RetryPolicy{MaxAttempts: 1} to disable retrying.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		c.retry = policy
+		return nil
+	}
+}
+
+// WithRateLimiter sets the rate limiter of the client.
+// The same limiter can be shared by several clients.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) error {
+		c.limiter = limiter
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the client
+func WithLogger(logger Logger) Option {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
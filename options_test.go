@@ -0,0 +1,157 @@
+package octopusenergyapi
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c, err := NewClient("testapikey", nil)
+		if assert.Nil(t, err) {
+			assert.Equal(t, http.DefaultClient, c.httpClient)
+			assert.Equal(t, defaultBaseURL, c.baseURL)
+			assert.Equal(t, DefaultRetryPolicy, c.retry)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		httpClient := &http.Client{}
+		limiter := NewRateLimiter(1, 1)
+		logger := log.New(&bytes.Buffer{}, "", 0)
+
+		c, err := NewClient("testapikey", http.DefaultClient,
+			WithBaseURL("http://localhost:8080/v1/"),
+			WithHTTPClient(httpClient),
+			WithUserAgent("test-agent"),
+			WithRetry(RetryPolicy{MaxAttempts: 1}),
+			WithRateLimiter(limiter),
+			WithLogger(logger),
+		)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "http://localhost:8080/v1", c.baseURL)
+			assert.Equal(t, httpClient, c.httpClient)
+			assert.Equal(t, "test-agent", c.userAgent)
+			assert.Equal(t, RetryPolicy{MaxAttempts: 1}, c.retry)
+			assert.Equal(t, limiter, c.limiter)
+			assert.Equal(t, logger, c.logger)
+		}
+	})
+
+	t.Run("invalid_base_url", func(t *testing.T) {
+		for _, URL := range []string{"", "/v1", "10928301####$$$%%"} {
+			_, err := NewClient("testapikey", nil, WithBaseURL(URL))
+			if assert.NotNil(t, err, URL) {
+				assert.Contains(t, err.Error(), "invalid option")
+			}
+		}
+	})
+
+	t.Run("nil_http_client", func(t *testing.T) {
+		_, err := NewClient("testapikey", nil, WithHTTPClient(nil))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNextPath(t *testing.T) {
+	c, err := NewClient("testapikey", nil, WithBaseURL("http://localhost:8080/proxy/v1"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	tests := []struct {
+		next     string
+		expected string
+	}{
+		{"", ""},
+		{"http://localhost:8080/proxy/v1/products/?page=2", "products/?page=2"},
+		{"https://api.octopus.energy/v1/products/?page=2&is_green=true", "products/?page=2&is_green=true"},
+		{"https://api.octopus.energy/v1/electricity-meter-points/0123/meters/0123/consumption/?page=3", "electricity-meter-points/0123/meters/0123/consumption/?page=3"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, c.nextPath(test.next), test.next)
+	}
+
+	// Base URL without a path
+	c, err = NewClient("testapikey", nil, WithBaseURL("http://localhost:8080"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "products/?page=2", c.nextPath("http://localhost:8080/products/?page=2"))
+		assert.Equal(t, "products/?page=2", c.nextPath("https://api.octopus.energy/v1/products/?page=2"))
+		assert.Equal(t, "products/?page=2", c.nextPath("http://127.0.0.1:8081/v1/products/?page=2"))
+	}
+}
+
+func TestBaseURLPagination(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mock/v1/products/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+
+		// Links to the next page point to the mock server, then to the real API
+		var next string
+		switch r.URL.Query().Get("page") {
+		case "":
+			next = fmt.Sprintf(`"%s/mock/v1/products/?page=2"`, server.URL)
+		case "2":
+			next = `"https://api.octopus.energy/v1/products/?page=3"`
+		default:
+			next = "null"
+		}
+
+		_, err := fmt.Fprintf(w, `{"count":3,"next":%s,"previous":null,"results":[{"code":"PAGE-%s"}]}`, next, r.URL.Query().Get("page"))
+		assert.Nil(t, err)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewClient("fakeapikey", server.Client(),
+		WithBaseURL(server.URL+"/mock/v1"),
+		WithUserAgent("test-agent"),
+	)
+	if assert.Nil(t, err) {
+		products, err := client.ListProducts()
+		if assert.Nil(t, err) && assert.Len(t, products, 3) {
+			assert.Equal(t, "PAGE-", products[0].Code)
+			assert.Equal(t, "PAGE-2", products[1].Code)
+			assert.Equal(t, "PAGE-3", products[2].Code)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	attempts := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		_, err := w.Write([]byte(`{"gsp":"_A","mpan":"0123456789","profile_class":1}`))
+		assert.Nil(t, err)
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client, err := NewClient("fakeapikey", server.Client(),
+		WithBaseURL(server.URL),
+		WithRetry(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		WithLogger(log.New(&buf, "", 0)),
+	)
+	if assert.Nil(t, err) {
+		_, err = client.GetMeterPoint("0123456789")
+		if assert.Nil(t, err) {
+			assert.Contains(t, buf.String(), "retrying electricity-meter-points/0123456789/")
+			assert.Contains(t, buf.String(), "code 502")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package octopusenergyapi
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Page represents a single page of results returned by a list endpoint
+type Page[T any] struct {
+	// Count is the total number of results across all pages
+	Count    int    `json:"count"`
+	Next     string `json:"next"`
+	Previous string `json:"previous"`
+	Results  []T    `json:"results"`
+}
+
+// paginate returns an iterator over results of all pages, starting at path.
+// Pages are only retrieved as the iteration progresses, so stopping early
+// doesn't retrieve the remaining ones. Errors are wrapped with msg.
+//
+// The iterator is compatible with iter.Seq2[T, error].
+func paginate[T any](ctx context.Context, c *Client, path, msg string) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for URL := path; URL != ""; {
+			var page Page[T]
+
+			if err := c.do(ctx, URL, &page); err != nil {
+				var zero T
+				yield(zero, errors.Wrap(err, msg))
+				return
+			}
+
+			for _, result := range page.Results {
+				if !yield(result, nil) {
+					return
+				}
+			}
+
+			URL = c.nextPath(page.Next)
+		}
+	}
+}
+
+// collectPages retrieves results of all pages, starting at path, into
+// a single page with the total count reported by the API.
+// Errors are wrapped with msg.
+func collectPages[T any](ctx context.Context, c *Client, path, msg string) (Page[T], error) {
+	var all Page[T]
+
+	for URL := path; URL != ""; {
+		var page Page[T]
+
+		if err := c.do(ctx, URL, &page); err != nil {
+			return Page[T]{}, errors.Wrap(err, msg)
+		}
+
+		all.Count = page.Count
+		all.Results = append(all.Results, page.Results...)
+
+		URL = c.nextPath(page.Next)
+	}
+
+	return all, nil
+}
+
+// seqError returns an iterator yielding only err
+func seqError[T any](err error) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
+
+// collect retrieves all results of an iterator, stopping at the first error
+func collect[T any](seq func(yield func(T, error) bool)) ([]T, error) {
+	var results []T
+	var err error
+
+	seq(func(result T, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+
+		results = append(results, result)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
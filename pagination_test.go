@@ -0,0 +1,145 @@
+package octopusenergyapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedServer serves pages of 2 results each, counting the requests
+func pagedServer(t *testing.T, pages int, requests *int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			_, err := fmt.Sscan(p, &page)
+			assert.Nil(t, err)
+		}
+
+		next := "null"
+		if page < pages {
+			next = fmt.Sprintf(`"%s%s?page=%d"`, server.URL, r.URL.Path, page+1)
+		}
+
+		_, err := fmt.Fprintf(w, `{"count":%d,"next":%s,"previous":null,"results":[{"code":"P%d-1","group_id":"_A"},{"code":"P%d-2","group_id":"_B"}]}`,
+			pages*2, next, page, page)
+		assert.Nil(t, err)
+	}))
+
+	return server
+}
+
+func TestPaginate(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		var requests int32
+		server := pagedServer(t, 3, &requests)
+		defer server.Close()
+
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL))
+		if assert.Nil(t, err) {
+			products, err := collect(client.ProductsSeq(context.Background(), ListProductsOption{}))
+			if assert.Nil(t, err) && assert.Len(t, products, 6) {
+				assert.Equal(t, "P1-1", products[0].Code)
+				assert.Equal(t, "P3-2", products[5].Code)
+			}
+			assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+		}
+	})
+
+	t.Run("stop_early", func(t *testing.T) {
+		var requests int32
+		server := pagedServer(t, 10, &requests)
+		defer server.Close()
+
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL))
+		if assert.Nil(t, err) {
+			var codes []string
+			client.ProductsSeq(context.Background(), ListProductsOption{})(func(p Product, err error) bool {
+				assert.Nil(t, err)
+				codes = append(codes, p.Code)
+				return len(codes) < 3
+			})
+
+			assert.Equal(t, []string{"P1-1", "P1-2", "P2-1"}, codes)
+			// Remaining pages are not retrieved
+			assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL))
+		if assert.Nil(t, err) {
+			calls := 0
+			client.ElecStandardUnitRatesSeq(context.Background(), "P", "T", RateOption{})(func(r Rate, err error) bool {
+				calls++
+				if assert.NotNil(t, err) {
+					assert.True(t, errors.Is(err, ErrNotFound))
+					assert.Contains(t, err.Error(), "error retrieving standard-unit-rates")
+				}
+				return true
+			})
+			assert.Equal(t, 1, calls)
+		}
+	})
+}
+
+func TestSeqEndpoints(t *testing.T) {
+	var requests int32
+	server := pagedServer(t, 2, &requests)
+	defer server.Close()
+
+	client, err := NewClient("fakeapikey", server.Client(), WithBaseURL(server.URL))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	rateSeqs := map[string]func(context.Context, string, string, RateOption) func(func(Rate, error) bool){
+		"elec_standard_unit_rates": client.ElecStandardUnitRatesSeq,
+		"gas_standard_unit_rates":  client.GasStandardUnitRatesSeq,
+		"elec_day_unit_rates":      client.ElecDayUnitRatesSeq,
+		"elec_night_unit_rates":    client.ElecNightUnitRatesSeq,
+		"elec_standing_charges":    client.ElecStandingChargesSeq,
+		"gas_standing_charges":     client.GasStandingChargesSeq,
+	}
+	for name, seq := range rateSeqs {
+		rates, err := collect(seq(ctx, "P", "T", RateOption{}))
+		if assert.Nil(t, err, name) {
+			assert.Len(t, rates, 4, name)
+		}
+	}
+
+	cons, err := collect(client.ElecMeterConsumptionSeq(ctx, "0123456789", "0123456789", ConsumptionOption{}))
+	if assert.Nil(t, err) {
+		assert.Len(t, cons, 4)
+	}
+
+	cons, err = collect(client.GasMeterConsumptionSeq(ctx, "0123456789", "0123456789", ConsumptionOption{}))
+	if assert.Nil(t, err) {
+		assert.Len(t, cons, 4)
+	}
+
+	gsps, err := collect(client.GridSupplyPointsSeq(ctx, ""))
+	if assert.Nil(t, err) && assert.Len(t, gsps, 4) {
+		assert.Equal(t, GSPs[0], gsps[0])
+		assert.Equal(t, GSPs[1], gsps[1])
+	}
+
+	_, err = collect(client.GridSupplyPointsSeq(ctx, "invalid postcode"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid postcode")
+	}
+}
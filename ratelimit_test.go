@@ -0,0 +1,99 @@
+package octopusenergyapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("burst", func(t *testing.T) {
+		l := NewRateLimiter(1, 3)
+
+		// Burst is available immediately
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Duration(0), l.reserve())
+		}
+		assert.Greater(t, l.reserve(), time.Duration(0))
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		l := NewRateLimiter(100, 1)
+
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			assert.Nil(t, l.Wait(context.Background()))
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		l := NewRateLimiter(200, 5)
+
+		var count int32
+		var wg sync.WaitGroup
+		start := time.Now()
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if assert.Nil(t, l.Wait(context.Background())) {
+					atomic.AddInt32(&count, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(20), count)
+		// 5 requests are allowed straight away, the remaining 15 at 200 rps
+		assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+	})
+
+	t.Run("context_cancelled", func(t *testing.T) {
+		l := NewRateLimiter(0.001, 1)
+		assert.Nil(t, l.Wait(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		var l *RateLimiter
+		assert.Nil(t, l.Wait(context.Background()))
+
+		l = NewRateLimiter(0, 1)
+		for i := 0; i < 100; i++ {
+			assert.Nil(t, l.Wait(context.Background()))
+		}
+	})
+}
+
+func TestClientRateLimit(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"gsp":"_A","mpan":"0123456789","profile_class":1}`))
+		assert.Nil(t, err)
+	})
+	httpClient, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	client, err := NewClient("fakeapikey", httpClient, WithRateLimiter(NewRateLimiter(0.001, 1)))
+	if assert.Nil(t, err) {
+		_, err = client.GetMeterPoint("0123456789")
+		assert.Nil(t, err)
+
+		// Second request has to wait for a token
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = client.GetMeterPointContext(ctx, "0123456789")
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "rate limiter")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package octopusenergyapi
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// DefaultRetryPolicy retries a request up to three times
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  defaultMinBackoff,
+	MaxBackoff:  defaultMaxBackoff,
+}
+
+// RetryPolicy configures retrying of failed requests.
+// Requests are retried on timeouts, connection resets and on responses with
+// 429 or 5xx status code. Only GET requests are made by the Client,
+// so all requests are safe to repeat.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// Values lower than 2 disable retrying.
+	MaxAttempts int
+	// MinBackoff is the base delay before the first retry, doubled for each
+	// subsequent one. Defaults to 500ms.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 30s.
+	// If the server asks for a longer delay in Retry-After header,
+	// the request is not retried.
+	MaxBackoff time.Duration
+}
+
+// backoff returns delay before the next attempt, after a given number of
+// failed attempts. It returns false if the delay requested by the server
+// exceeds MaxBackoff.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	minBackoff, maxBackoff := p.MinBackoff, p.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= maxBackoff
+	}
+
+	d := minBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	// Add jitter, so that concurrent clients don't retry in lockstep
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)), true
+}
+
+// isRetryable checks if a failed request can be repeated
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	// Only transient network failures are retried, not e.g. certificate
+	// or DNS errors, which are reported as net.Error as well
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter parses value of Retry-After header, which is either
+// a number of seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// sleep waits for a given duration or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
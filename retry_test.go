@@ -0,0 +1,217 @@
+package octopusenergyapi
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts: 5,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	}
+
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		min        time.Duration
+		max        time.Duration
+		ok         bool
+	}{
+		{1, 0, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{2, 0, 100 * time.Millisecond, 200 * time.Millisecond, true},
+		{3, 0, 150 * time.Millisecond, 300 * time.Millisecond, true},
+		{10, 0, 150 * time.Millisecond, 300 * time.Millisecond, true},
+		{1, 200 * time.Millisecond, 200 * time.Millisecond, 200 * time.Millisecond, true},
+		{1, time.Minute, time.Minute, time.Minute, false},
+	}
+
+	for _, test := range tests {
+		d, ok := p.backoff(test.attempt, test.retryAfter)
+		assert.Equal(t, test.ok, ok)
+		assert.GreaterOrEqual(t, d, test.min)
+		assert.LessOrEqual(t, d, test.max)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.octopus.energy/v1/products/", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", urlErr(&net.DNSError{Err: "i/o timeout", IsTimeout: true}), true},
+		{"connection_reset", urlErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"unexpected_eof", urlErr(io.ErrUnexpectedEOF), true},
+		{"server_error", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"not_found", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"dns", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}), false},
+		{"certificate", urlErr(x509.UnknownAuthorityError{}), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, isRetryable(context.Background(), errors.Wrap(test.err, "http get error")))
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid"))
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120"))
+
+	d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.Greater(t, d, 59*time.Minute)
+	assert.LessOrEqual(t, d, time.Hour)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name       string
+		statusCode int
+		failures   int32
+		attempts   int32
+		pass       bool
+	}{
+		{"recovers", http.StatusServiceUnavailable, 2, 3, true},
+		{"too_many_requests", http.StatusTooManyRequests, 1, 2, true},
+		{"exhausted", http.StatusInternalServerError, 5, 3, false},
+		{"not_retryable", http.StatusNotFound, 5, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) <= test.failures {
+					w.WriteHeader(test.statusCode)
+					return
+				}
+
+				_, err := w.Write([]byte(`{"gsp":"_A","mpan":"0123456789","profile_class":1}`))
+				assert.Nil(t, err)
+			})
+			httpClient, teardown := testingHTTPClient(h)
+			defer teardown()
+
+			client, err := NewClient("fakeapikey", httpClient, WithRetry(policy))
+			if assert.Nil(t, err) {
+				_, err = client.GetMeterPoint("0123456789")
+				if test.pass {
+					assert.Nil(t, err)
+				} else if assert.NotNil(t, err) {
+					var apiErr *APIError
+					if assert.True(t, errors.As(err, &apiErr)) {
+						assert.Equal(t, test.statusCode, apiErr.StatusCode)
+					}
+				}
+				assert.Equal(t, test.attempts, atomic.LoadInt32(&attempts))
+			}
+		})
+	}
+
+	t.Run("network_error", func(t *testing.T) {
+		httpClient, teardown := testingHTTPClient(nil, true)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient, WithRetry(policy))
+		if assert.Nil(t, err) {
+			_, err = client.GetMeterPoint("0123456789")
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "http get error")
+			}
+		}
+	})
+
+	t.Run("certificate_error", func(t *testing.T) {
+		var attempts int32
+		s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer s.Close()
+
+		httpClient := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					atomic.AddInt32(&attempts, 1)
+					return (&net.Dialer{}).DialContext(ctx, network, s.Listener.Addr().String())
+				},
+			},
+		}
+
+		client, err := NewClient("fakeapikey", httpClient, WithRetry(policy))
+		if assert.Nil(t, err) {
+			_, err = client.GetMeterPoint("0123456789")
+			assert.NotNil(t, err)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+		}
+	})
+
+	t.Run("context_cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var attempts int32
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient, WithRetry(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Hour}))
+		if assert.Nil(t, err) {
+			_, err = client.GetMeterPointContext(ctx, "0123456789")
+			assert.NotNil(t, err)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+		}
+	})
+
+	t.Run("retry_after_too_long", func(t *testing.T) {
+		var attempts int32
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		httpClient, teardown := testingHTTPClient(h)
+		defer teardown()
+
+		client, err := NewClient("fakeapikey", httpClient, WithRetry(policy))
+		if assert.Nil(t, err) {
+			_, err = client.GetMeterPoint("0123456789")
+			var apiErr *APIError
+			if assert.True(t, errors.As(err, &apiErr)) {
+				assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+				assert.Equal(t, 24*time.Hour, apiErr.RetryAfter)
+			}
+			assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+		}
+	})
+}
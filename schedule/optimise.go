@@ -0,0 +1,464 @@
+package schedule
+
+import (
+	"math"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// ErrInfeasible is returned when loads cannot be scheduled within their
+// constraints
+var ErrInfeasible = errors.New("loads cannot be scheduled")
+
+// epsilon is the tolerance of energy comparisons in kWh
+const epsilon = 1e-9
+
+// Load is a flexible load, such as an immersion heater or an electric vehicle
+type Load struct {
+	Name string
+	// Energy needed in kWh
+	Energy float64
+	// MaxPower is the maximum power drawn in kW
+	MaxPower float64
+	// AllowedHours are hours of the day (0-23) in which the load can run,
+	// in PlanOption.Location. Empty allows all hours.
+	AllowedHours []int
+	// Deadline is the time by which the load must finish, defaults to the
+	// end of the plan
+	Deadline time.Time
+	// Contiguous loads run at MaxPower without interruption until finished
+	Contiguous bool
+}
+
+// PlanOption represents optional parameters for Optimise
+type PlanOption struct {
+	// Start and End limit the planned period. Start defaults to the start of
+	// the earliest rate. End defaults to the latest deadline, or to the end of
+	// the latest rate if a load has no deadline.
+	Start time.Time
+	End   time.Time
+	// SlotDuration is the duration of planned slots, defaults to 30 minutes
+	SlotDuration time.Duration
+	// ImportLimit is the maximum power imported by the household in kW,
+	// zero means unlimited
+	ImportLimit float64
+	// Location determines hours of the day of AllowedHours, defaults to UTC
+	Location *time.Location
+}
+
+// Slot is the energy used by loads in a slot
+type Slot struct {
+	Start time.Time
+	End   time.Time
+	// RateExcVAT and RateIncVAT are average unit rates in p/kWh
+	RateExcVAT float64
+	RateIncVAT float64
+	// Energy is the energy in kWh used by each load, in order of loads
+	Energy []float64
+}
+
+// Total returns the energy in kWh used by all loads
+func (s Slot) Total() float64 {
+	var total float64
+	for _, e := range s.Energy {
+		total += e
+	}
+
+	return total
+}
+
+// Plan is a schedule of loads
+type Plan struct {
+	// Slots are the planned slots, ordered by time. Slots not covered by
+	// rates are left out.
+	Slots []Slot
+	// CostExcVAT and CostIncVAT are total costs in pence
+	CostExcVAT float64
+	CostIncVAT float64
+}
+
+// Optimise allocates energy of loads to slots, minimising the total cost
+// including VAT. Rates can be in any order, e.g. as returned by the client.
+//
+// Loads that are not contiguous are allocated optimally. Contiguous loads are
+// placed first, one at a time in order of loads, each at the start which
+// minimises the total cost given the loads placed before it.
+func Optimise(rates []octopusenergyapi.Rate, loads []Load, options PlanOption) (Plan, error) {
+	for _, l := range loads {
+		if l.Energy < 0 || math.IsNaN(l.Energy) {
+			return Plan{}, errors.Errorf("invalid energy %g of load %s", l.Energy, l.Name)
+		}
+		if l.Energy > 0 && !(l.MaxPower > 0) {
+			return Plan{}, errors.Errorf("invalid maximum power %g of load %s", l.MaxPower, l.Name)
+		}
+		for _, h := range l.AllowedHours {
+			if h < 0 || h > 23 {
+				return Plan{}, errors.Errorf("invalid hour %d of load %s", h, l.Name)
+			}
+		}
+	}
+
+	slots, err := newSlots(newSeries(rates), loads, options)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	p := newPlanner(slots, loads, options)
+
+	for i, l := range loads {
+		if l.Contiguous && l.Energy > 0 {
+			if err := p.placeContiguous(i); err != nil {
+				return Plan{}, err
+			}
+		}
+	}
+
+	energy, _, ok := p.flexible()
+	if !ok {
+		return Plan{}, p.infeasible()
+	}
+	for i := range loads {
+		if !loads[i].Contiguous {
+			p.energy[i] = energy[i]
+		}
+	}
+
+	plan := Plan{Slots: slots}
+	for j := range plan.Slots {
+		s := &plan.Slots[j]
+		s.Energy = make([]float64, len(loads))
+		for i := range loads {
+			s.Energy[i] = p.energy[i][j]
+		}
+
+		plan.CostExcVAT += s.Total() * s.RateExcVAT
+		plan.CostIncVAT += s.Total() * s.RateIncVAT
+	}
+
+	return plan, nil
+}
+
+// newSlots divides the planned period into slots covered by rates
+func newSlots(s series, loads []Load, options PlanOption) ([]Slot, error) {
+	if len(s) == 0 {
+		return nil, errors.Wrap(ErrInfeasible, "no rates")
+	}
+
+	duration := options.SlotDuration
+	if duration == 0 {
+		duration = 30 * time.Minute
+	}
+	if duration < 0 {
+		return nil, errors.Errorf("invalid slot duration %s", duration)
+	}
+
+	start := options.Start
+	if start.IsZero() {
+		start = s[0].ValidFrom
+	}
+
+	end := options.End
+	if end.IsZero() {
+		for _, l := range loads {
+			if l.Deadline.IsZero() {
+				end = time.Time{}
+				break
+			}
+			if l.Deadline.After(end) {
+				end = l.Deadline
+			}
+		}
+	}
+	if end.IsZero() {
+		for i := range s {
+			if e := s.end(i); e.After(end) {
+				end = e
+			}
+		}
+	}
+	if end.IsZero() {
+		return nil, errors.New("end of plan is not set and the latest rate does not expire")
+	}
+
+	var slots []Slot
+	for t := start; t.Before(end); t = t.Add(duration) {
+		slotEnd := t.Add(duration)
+		if slotEnd.After(end) {
+			slotEnd = end
+		}
+
+		w, ok := s.window(t, slotEnd)
+		if !ok {
+			continue
+		}
+		slots = append(slots, Slot{Start: t, End: slotEnd, RateExcVAT: w.AverageExcVAT, RateIncVAT: w.AverageIncVAT})
+	}
+
+	return slots, nil
+}
+
+// planner holds state of an optimisation
+type planner struct {
+	slots []Slot
+	loads []Load
+	// allowed is set for slots in which a load can run
+	allowed [][]bool
+	// capacity is energy in kWh available to loads in a slot
+	capacity []float64
+	// energy is energy allocated to a load in a slot
+	energy [][]float64
+}
+
+func newPlanner(slots []Slot, loads []Load, options PlanOption) *planner {
+	loc := options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	p := &planner{
+		slots:    slots,
+		loads:    loads,
+		allowed:  make([][]bool, len(loads)),
+		capacity: make([]float64, len(slots)),
+		energy:   make([][]float64, len(loads)),
+	}
+
+	for j, s := range slots {
+		p.capacity[j] = math.Inf(1)
+		if options.ImportLimit > 0 {
+			p.capacity[j] = options.ImportLimit * s.End.Sub(s.Start).Hours()
+		}
+	}
+
+	for i, l := range loads {
+		hours := make(map[int]bool, len(l.AllowedHours))
+		for _, h := range l.AllowedHours {
+			hours[h] = true
+		}
+
+		p.allowed[i] = make([]bool, len(slots))
+		p.energy[i] = make([]float64, len(slots))
+		for j, s := range slots {
+			if !l.Deadline.IsZero() && s.End.After(l.Deadline) {
+				continue
+			}
+			if len(hours) > 0 && !hours[s.Start.In(loc).Hour()] {
+				continue
+			}
+			p.allowed[i][j] = true
+		}
+	}
+
+	return p
+}
+
+// contiguous returns energy of load i in each slot when it starts in slot
+// start, or false if it cannot run there
+func (p *planner) contiguous(i, start int) ([]float64, bool) {
+	l := p.loads[i]
+	energy := make([]float64, len(p.slots))
+
+	remaining := l.Energy
+	for j := start; remaining > epsilon; j++ {
+		if j >= len(p.slots) || !p.allowed[i][j] {
+			return nil, false
+		}
+		if j > start && !p.slots[j].Start.Equal(p.slots[j-1].End) {
+			// Gap in rates
+			return nil, false
+		}
+
+		e := math.Min(remaining, l.MaxPower*p.slots[j].End.Sub(p.slots[j].Start).Hours())
+		if p.capacity[j] < e-epsilon {
+			return nil, false
+		}
+
+		energy[j] = e
+		remaining -= e
+	}
+
+	return energy, true
+}
+
+// placeContiguous places load i at the start with the lowest total cost
+func (p *planner) placeContiguous(i int) error {
+	var best []float64
+	bestCost := math.Inf(1)
+
+	for start := range p.slots {
+		energy, ok := p.contiguous(i, start)
+		if !ok {
+			continue
+		}
+
+		var cost float64
+		for j, e := range energy {
+			cost += e * p.slots[j].RateIncVAT
+			p.capacity[j] -= e
+		}
+
+		_, flexibleCost, ok := p.flexible()
+		for j, e := range energy {
+			p.capacity[j] += e
+		}
+
+		if ok && cost+flexibleCost < bestCost-epsilon {
+			best, bestCost = energy, cost+flexibleCost
+		}
+	}
+
+	if best == nil {
+		return errors.Wrapf(ErrInfeasible, "load %s", p.loads[i].Name)
+	}
+
+	p.energy[i] = best
+	for j, e := range best {
+		p.capacity[j] -= e
+	}
+
+	return nil
+}
+
+// flexible allocates loads which are not contiguous at the lowest cost,
+// within the remaining capacity. It returns false if they do not fit.
+func (p *planner) flexible() ([][]float64, float64, bool) {
+	// Network from the source through loads and slots to the sink
+	source, sink := 0, 1+len(p.loads)+len(p.slots)
+	g := make(graph, sink+1)
+	slotNode := func(j int) int { return 1 + len(p.loads) + j }
+
+	type arc struct{ load, slot, node, edge int }
+	var arcs []arc
+	var total float64
+
+	for i, l := range p.loads {
+		if l.Contiguous || l.Energy <= 0 {
+			continue
+		}
+		total += l.Energy
+		g.add(source, 1+i, l.Energy, 0)
+
+		for j, s := range p.slots {
+			if !p.allowed[i][j] || p.capacity[j] <= epsilon {
+				continue
+			}
+			arcs = append(arcs, arc{i, j, 1 + i, len(g[1+i])})
+			g.add(1+i, slotNode(j), l.MaxPower*s.End.Sub(s.Start).Hours(), 0)
+		}
+	}
+	energy := make([][]float64, len(p.loads))
+	for i := range energy {
+		energy[i] = make([]float64, len(p.slots))
+	}
+	if total == 0 {
+		return energy, 0, true
+	}
+
+	for j, s := range p.slots {
+		// Earlier slots are preferred at the same rate
+		g.add(slotNode(j), sink, p.capacity[j], s.RateIncVAT+float64(j)*epsilon)
+	}
+
+	if flow := g.minCostFlow(source, sink, total); flow < total-epsilon*math.Max(1, total) {
+		return nil, 0, false
+	}
+
+	var cost float64
+	for _, a := range arcs {
+		e := g[a.node][a.edge]
+		flow := g[e.to][e.rev].cap
+		energy[a.load][a.slot] = flow
+		cost += flow * p.slots[a.slot].RateIncVAT
+	}
+
+	return energy, cost, true
+}
+
+// infeasible returns an error naming the first load which cannot be allocated
+func (p *planner) infeasible() error {
+	for i, l := range p.loads {
+		if l.Contiguous || l.Energy <= 0 {
+			continue
+		}
+
+		var available float64
+		for j, s := range p.slots {
+			if p.allowed[i][j] {
+				available += math.Min(p.capacity[j], l.MaxPower*s.End.Sub(s.Start).Hours())
+			}
+		}
+		if available < l.Energy-epsilon {
+			return errors.Wrapf(ErrInfeasible, "load %s", l.Name)
+		}
+	}
+
+	return errors.Wrap(ErrInfeasible, "import limit exceeded")
+}
+
+// graph is a flow network with residual edges
+type graph [][]edge
+
+type edge struct {
+	to   int
+	cap  float64
+	cost float64
+	// rev is the position of the reverse edge in g[to]
+	rev int
+}
+
+func (g graph) add(from, to int, capacity, cost float64) {
+	g[from] = append(g[from], edge{to: to, cap: capacity, cost: cost, rev: len(g[to])})
+	g[to] = append(g[to], edge{to: from, cap: 0, cost: -cost, rev: len(g[from]) - 1})
+}
+
+// minCostFlow sends up to limit units from source to sink along cheapest
+// paths and returns the flow sent
+func (g graph) minCostFlow(source, sink int, limit float64) float64 {
+	var flow float64
+
+	for flow < limit-epsilon {
+		// Bellman-Ford, as residual edges have negative costs
+		dist := make([]float64, len(g))
+		prevNode := make([]int, len(g))
+		prevEdge := make([]int, len(g))
+		for v := range dist {
+			dist[v] = math.Inf(1)
+		}
+		dist[source] = 0
+
+		for changed, n := true, 0; changed && n < len(g); n++ {
+			changed = false
+			for u := range g {
+				if math.IsInf(dist[u], 1) {
+					continue
+				}
+				for k, e := range g[u] {
+					if e.cap > epsilon && dist[u]+e.cost < dist[e.to]-1e-12 {
+						dist[e.to] = dist[u] + e.cost
+						prevNode[e.to], prevEdge[e.to] = u, k
+						changed = true
+					}
+				}
+			}
+		}
+		if math.IsInf(dist[sink], 1) {
+			break
+		}
+
+		push := limit - flow
+		for v := sink; v != source; v = prevNode[v] {
+			push = math.Min(push, g[prevNode[v]][prevEdge[v]].cap)
+		}
+		for v := sink; v != source; v = prevNode[v] {
+			e := &g[prevNode[v]][prevEdge[v]]
+			e.cap -= push
+			g[v][e.rev].cap += push
+		}
+
+		flow += push
+	}
+
+	return flow
+}
@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadEnergy returns energy of load i in each slot
+func loadEnergy(plan Plan, i int) []float64 {
+	energy := make([]float64, len(plan.Slots))
+	for j, s := range plan.Slots {
+		energy[j] = s.Energy[i]
+	}
+
+	return energy
+}
+
+func assertEnergy(t *testing.T, expected, actual []float64) {
+	if assert.Len(t, actual, len(expected)) {
+		for j := range expected {
+			assert.InDelta(t, expected[j], actual[j], 1e-6, "slot %d", j)
+		}
+	}
+}
+
+func TestOptimiseFlexible(t *testing.T) {
+	// Rates are 10, 5, 3, 4, 20, 1, 30, 8
+	loads := []Load{{Name: "immersion", Energy: 3, MaxPower: 2}}
+
+	plan, err := Optimise(testRates, loads, PlanOption{})
+	if assert.Nil(t, err) && assert.Len(t, plan.Slots, 8) {
+		assertEnergy(t, []float64{0, 0, 1, 1, 0, 1, 0, 0}, loadEnergy(plan, 0))
+		assert.Equal(t, slot(0), plan.Slots[0].Start)
+		assert.InDelta(t, 3, plan.Slots[2].RateExcVAT, 1e-6)
+		assert.InDelta(t, 8, plan.CostExcVAT, 1e-6)
+		assert.InDelta(t, 8.4, plan.CostIncVAT, 1e-6)
+	}
+}
+
+func TestOptimiseZeroEnergy(t *testing.T) {
+	loads := []Load{{Name: "immersion", MaxPower: 2}}
+
+	plan, err := Optimise(testRates, loads, PlanOption{})
+	if assert.Nil(t, err) && assert.Len(t, plan.Slots, 8) {
+		assertEnergy(t, make([]float64, 8), loadEnergy(plan, 0))
+		assert.Zero(t, plan.CostExcVAT)
+	}
+}
+
+func TestOptimiseImportLimit(t *testing.T) {
+	rates := halfHourly(1, 2, 9)
+
+	// Allocating the first load to the cheapest slots would leave no room
+	// for the second one
+	loads := []Load{
+		{Name: "car", Energy: 2, MaxPower: 7},
+		{Name: "dishwasher", Energy: 1, MaxPower: 2, Deadline: slot(1)},
+	}
+
+	plan, err := Optimise(rates, loads, PlanOption{ImportLimit: 2})
+	if assert.Nil(t, err) {
+		assertEnergy(t, []float64{0, 1, 1}, loadEnergy(plan, 0))
+		assertEnergy(t, []float64{1, 0, 0}, loadEnergy(plan, 1))
+		assert.InDelta(t, 12, plan.CostExcVAT, 1e-6)
+		for _, s := range plan.Slots {
+			assert.LessOrEqual(t, s.Total(), 1+1e-6)
+		}
+	}
+
+	_, err = Optimise(rates, loads, PlanOption{ImportLimit: 1})
+	assert.True(t, errors.Is(err, ErrInfeasible))
+}
+
+func TestOptimiseContiguous(t *testing.T) {
+	loads := []Load{{Name: "washing machine", Energy: 1.2, MaxPower: 1, Contiguous: true}}
+
+	plan, err := Optimise(testRates, loads, PlanOption{})
+	if assert.Nil(t, err) {
+		assertEnergy(t, []float64{0, 0.5, 0.5, 0.2, 0, 0, 0, 0}, loadEnergy(plan, 0))
+		assert.InDelta(t, 4.8, plan.CostExcVAT, 1e-6)
+	}
+
+	// The washing machine moves later, as the heater can only run in the
+	// first two hours
+	loads = append(loads, Load{Name: "heater", Energy: 1.5, MaxPower: 3, AllowedHours: []int{0, 1}})
+	plan, err = Optimise(testRates, loads, PlanOption{ImportLimit: 1})
+	if assert.Nil(t, err) {
+		assertEnergy(t, []float64{0.5, 0.5, 0.5, 0, 0, 0, 0, 0}, loadEnergy(plan, 1))
+		assertEnergy(t, []float64{0, 0, 0, 0.5, 0.5, 0.2, 0, 0}, loadEnergy(plan, 0))
+	}
+
+	// Does not fit before the deadline
+	loads = []Load{{Name: "oven", Energy: 2, MaxPower: 1, Contiguous: true, Deadline: slot(3)}}
+	_, err = Optimise(testRates, loads, PlanOption{})
+	if assert.True(t, errors.Is(err, ErrInfeasible)) {
+		assert.Contains(t, err.Error(), "oven")
+	}
+}
+
+func TestOptimiseOptions(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	// Hours in British Summer Time
+	rates := []octopusenergyapi.Rate{{ValueExcVAT: 10, ValueIncVAT: 10.5, ValidFrom: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}}
+	loads := []Load{{Name: "heater", Energy: 1, MaxPower: 3, AllowedHours: []int{2}}}
+
+	plan, err := Optimise(rates, loads, PlanOption{
+		End:          time.Date(2020, 6, 1, 6, 0, 0, 0, time.UTC),
+		SlotDuration: time.Hour,
+		Location:     london,
+	})
+	if assert.Nil(t, err) && assert.Len(t, plan.Slots, 6) {
+		assertEnergy(t, []float64{0, 1, 0, 0, 0, 0}, loadEnergy(plan, 0))
+	}
+
+	// The latest rate does not expire
+	_, err = Optimise(rates, loads, PlanOption{})
+	assert.NotNil(t, err)
+
+	_, err = Optimise(testRates, []Load{{Name: "invalid", Energy: 1}}, PlanOption{})
+	assert.NotNil(t, err)
+	_, err = Optimise(testRates, []Load{{Name: "invalid", Energy: 1, MaxPower: 1, AllowedHours: []int{24}}}, PlanOption{})
+	assert.NotNil(t, err)
+	_, err = Optimise(nil, nil, PlanOption{})
+	assert.True(t, errors.Is(err, ErrInfeasible))
+}
@@ -0,0 +1,218 @@
+// Package schedule finds the cheapest times to run flexible loads on
+// time-of-use tariffs, such as Agile, using unit rates returned by the API.
+package schedule
+
+import (
+	"sort"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+)
+
+// ErrNoWindow is returned when rates do not cover a window of the required
+// duration within the allowed period
+var ErrNoWindow = errors.New("no window found")
+
+// WindowOption represents optional parameters for CheapestWindow
+type WindowOption struct {
+	// Earliest is the earliest start of a window
+	Earliest time.Time
+	// Latest is the latest end of a window
+	Latest time.Time
+	// Count is the number of non-overlapping windows returned by
+	// CheapestWindows, defaults to 1
+	Count int
+}
+
+// Window is a contiguous block of time
+type Window struct {
+	Start time.Time
+	End   time.Time
+	// AverageExcVAT and AverageIncVAT are unit rates in p/kWh averaged over
+	// the window, weighted by time
+	AverageExcVAT float64
+	AverageIncVAT float64
+}
+
+// Cost returns the cost in pence of using energy in kWh evenly over the window
+func (w Window) Cost(energy float64) float64 {
+	return w.AverageIncVAT * energy
+}
+
+// CheapestWindow returns the contiguous window of duration with the lowest
+// average unit rate. Rates can be in any order; a window must be covered by
+// rates without gaps. Ties are resolved by the earliest start.
+func CheapestWindow(rates []octopusenergyapi.Rate, duration time.Duration, options WindowOption) (Window, error) {
+	options.Count = 1
+	windows, err := CheapestWindows(rates, duration, options)
+	if err != nil {
+		return Window{}, err
+	}
+
+	return windows[0], nil
+}
+
+// CheapestWindows returns up to options.Count non-overlapping windows of
+// duration, from the cheapest. Each window is the cheapest one not
+// overlapping those before it.
+func CheapestWindows(rates []octopusenergyapi.Rate, duration time.Duration, options WindowOption) ([]Window, error) {
+	if duration <= 0 {
+		return nil, errors.Errorf("invalid duration %s", duration)
+	}
+	count := options.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	series := newSeries(rates)
+
+	var windows []Window
+	for len(windows) < count {
+		w, ok := series.cheapest(duration, options, windows)
+		if !ok {
+			break
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, errors.Wrapf(ErrNoWindow, "duration %s", duration)
+	}
+
+	return windows, nil
+}
+
+// cheapest returns the cheapest window of duration not overlapping chosen
+// windows, resolving ties by the earliest start. Windows next to chosen ones
+// are candidates as well, as their edges bound the remaining time.
+func (s series) cheapest(duration time.Duration, options WindowOption, chosen []Window) (Window, bool) {
+	var best Window
+	var found bool
+	for _, start := range s.starts(duration, options, chosen) {
+		w, ok := s.window(start, start.Add(duration))
+		if !ok || overlaps(w, chosen) {
+			continue
+		}
+
+		if !found || w.AverageIncVAT < best.AverageIncVAT {
+			best, found = w, true
+		}
+	}
+
+	return best, found
+}
+
+// overlaps reports whether w overlaps any of windows
+func overlaps(w Window, windows []Window) bool {
+	for _, other := range windows {
+		if w.Start.Before(other.End) && other.Start.Before(w.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// series is a list of rates ordered by time
+type series []octopusenergyapi.Rate
+
+func newSeries(rates []octopusenergyapi.Rate) series {
+	s := append(series(nil), rates...)
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].ValidFrom.Before(s[j].ValidFrom)
+	})
+
+	return s
+}
+
+// end returns the end of the i-th rate, which is the start of the next rate
+// if it does not expire
+func (s series) end(i int) time.Time {
+	if !s[i].ValidTo.IsZero() {
+		return s[i].ValidTo
+	}
+	if i+1 < len(s) {
+		return s[i+1].ValidFrom
+	}
+
+	return time.Time{}
+}
+
+// starts returns candidate starts of windows. The average rate of a window
+// only changes direction when its start or end crosses a rate boundary, so
+// the cheapest window starts or ends at a boundary or at a limit, including
+// edges of chosen windows.
+func (s series) starts(duration time.Duration, options WindowOption, chosen []Window) []time.Time {
+	var starts []time.Time
+	add := func(t time.Time) {
+		if t.IsZero() {
+			return
+		}
+		if !options.Earliest.IsZero() && t.Before(options.Earliest) {
+			return
+		}
+		if !options.Latest.IsZero() && t.Add(duration).After(options.Latest) {
+			return
+		}
+		starts = append(starts, t)
+	}
+
+	add(options.Earliest)
+	if !options.Latest.IsZero() {
+		add(options.Latest.Add(-duration))
+	}
+	for i, r := range s {
+		add(r.ValidFrom)
+		if end := s.end(i); !end.IsZero() {
+			add(end.Add(-duration))
+		}
+	}
+	for _, w := range chosen {
+		add(w.End)
+		add(w.Start.Add(-duration))
+	}
+
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	return starts
+}
+
+// window calculates the average rate between from and to. It returns false
+// if the period is not covered by rates.
+func (s series) window(from, to time.Time) (Window, bool) {
+	w := Window{Start: from, End: to}
+
+	// Last rate starting at or before from
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].ValidFrom.After(from)
+	}) - 1
+	if i < 0 {
+		return Window{}, false
+	}
+
+	total := float64(to.Sub(from))
+	for t := from; t.Before(to); i++ {
+		if i >= len(s) || s[i].ValidFrom.After(t) {
+			// Gap between rates
+			return Window{}, false
+		}
+
+		end := s.end(i)
+		if !end.IsZero() && !end.After(t) {
+			return Window{}, false
+		}
+		if end.IsZero() || end.After(to) {
+			end = to
+		}
+
+		share := float64(end.Sub(t)) / total
+		w.AverageExcVAT += float64(s[i].ValueExcVAT) * share
+		w.AverageIncVAT += float64(s[i].ValueIncVAT) * share
+
+		t = end
+	}
+
+	return w, true
+}
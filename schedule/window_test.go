@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FileGo/octopusenergyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2020, 11, 26, 0, 0, 0, 0, time.UTC)
+
+// slot returns start of the i-th half-hour
+func slot(i int) time.Time {
+	return start.Add(time.Duration(i) * 30 * time.Minute)
+}
+
+// halfHourly returns half-hourly rates, most recent first as returned by the API
+func halfHourly(values ...float32) []octopusenergyapi.Rate {
+	rates := make([]octopusenergyapi.Rate, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		rates = append(rates, octopusenergyapi.Rate{
+			ValueExcVAT: values[i],
+			ValueIncVAT: values[i] * 1.05,
+			ValidFrom:   slot(i),
+			ValidTo:     slot(i + 1),
+		})
+	}
+
+	return rates
+}
+
+var testRates = halfHourly(10, 5, 3, 4, 20, 1, 30, 8)
+
+func TestCheapestWindow(t *testing.T) {
+	w, err := CheapestWindow(testRates, time.Hour, WindowOption{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(2), w.Start)
+		assert.Equal(t, slot(4), w.End)
+		assert.InDelta(t, 3.5, w.AverageExcVAT, 1e-6)
+		assert.InDelta(t, 3.675, w.AverageIncVAT, 1e-6)
+		assert.InDelta(t, 7.35, w.Cost(2), 1e-6)
+	}
+
+	// Window ends within a half-hour
+	w, err = CheapestWindow(testRates, 45*time.Minute, WindowOption{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(2), w.Start)
+		assert.Equal(t, slot(2).Add(45*time.Minute), w.End)
+		assert.InDelta(t, 10.0/3, w.AverageExcVAT, 1e-6)
+	}
+
+	// Whole series
+	w, err = CheapestWindow(testRates, 4*time.Hour, WindowOption{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(0), w.Start)
+		assert.InDelta(t, 10.125, w.AverageExcVAT, 1e-6)
+	}
+}
+
+func TestCheapestWindowLimits(t *testing.T) {
+	w, err := CheapestWindow(testRates, time.Hour, WindowOption{Earliest: slot(4)})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(4), w.Start)
+		assert.InDelta(t, 10.5, w.AverageExcVAT, 1e-6)
+	}
+
+	// Limits within half-hours
+	w, err = CheapestWindow(testRates, time.Hour, WindowOption{
+		Earliest: slot(0).Add(15 * time.Minute),
+		Latest:   slot(3).Add(15 * time.Minute),
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(1).Add(15*time.Minute), w.Start)
+		assert.Equal(t, slot(3).Add(15*time.Minute), w.End)
+		assert.InDelta(t, 3.75, w.AverageExcVAT, 1e-6)
+	}
+
+	_, err = CheapestWindow(testRates, time.Hour, WindowOption{Earliest: slot(7)})
+	assert.True(t, errors.Is(err, ErrNoWindow))
+}
+
+func TestCheapestWindows(t *testing.T) {
+	windows, err := CheapestWindows(testRates, time.Hour, WindowOption{Count: 3})
+	if assert.Nil(t, err) && assert.Len(t, windows, 3) {
+		assert.Equal(t, slot(2), windows[0].Start)
+		assert.Equal(t, slot(0), windows[1].Start)
+		assert.Equal(t, slot(4), windows[2].Start)
+	}
+
+	// Fewer windows fit
+	windows, err = CheapestWindows(testRates, 3*time.Hour, WindowOption{Count: 2})
+	if assert.Nil(t, err) {
+		assert.Len(t, windows, 1)
+	}
+
+	// The second window starts at the end of the first one
+	windows, err = CheapestWindows(halfHourly(1, 1, 5, 100), 40*time.Minute, WindowOption{Count: 2})
+	if assert.Nil(t, err) && assert.Len(t, windows, 2) {
+		assert.Equal(t, slot(0), windows[0].Start)
+		assert.Equal(t, slot(0).Add(40*time.Minute), windows[1].Start)
+		assert.Equal(t, slot(0).Add(80*time.Minute), windows[1].End)
+		assert.InDelta(t, 3, windows[1].AverageExcVAT, 1e-6)
+	}
+}
+
+func TestCheapestWindowGaps(t *testing.T) {
+	// Rate at slot 3 is missing, the last rate does not expire
+	rates := append(halfHourly(10, 5, 3), halfHourly(0, 0, 0, 0, 20, 1)[:2]...)
+	rates = append(rates, octopusenergyapi.Rate{ValueExcVAT: 2, ValueIncVAT: 2.1, ValidFrom: slot(6)})
+
+	w, err := CheapestWindow(rates, time.Hour, WindowOption{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(5), w.Start)
+		assert.InDelta(t, 1.5, w.AverageExcVAT, 1e-6)
+	}
+
+	// Open-ended rate is limited by Latest
+	w, err = CheapestWindow(rates, time.Hour, WindowOption{Earliest: slot(6), Latest: slot(10)})
+	if assert.Nil(t, err) {
+		assert.Equal(t, slot(6), w.Start)
+		assert.InDelta(t, 2, w.AverageExcVAT, 1e-6)
+	}
+
+	_, err = CheapestWindow(rates, 2*time.Hour, WindowOption{Latest: slot(5)})
+	assert.True(t, errors.Is(err, ErrNoWindow))
+
+	_, err = CheapestWindow(nil, time.Hour, WindowOption{})
+	assert.True(t, errors.Is(err, ErrNoWindow))
+
+	_, err = CheapestWindow(testRates, 0, WindowOption{})
+	assert.NotNil(t, err)
+}
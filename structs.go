@@ -7,9 +7,14 @@ import (
 
 const (
 	iso8601         = "2006-01-02T15:04:05.000+0000"
-	baseURL         = "https://api.octopus.energy/v1"
+	defaultBaseURL  = "https://api.octopus.energy/v1"
 	fuelElectricity = "electricity"
 	fuelGas         = "gas"
+
+	chargeStandardUnitRates = "standard-unit-rates"
+	chargeStandingCharges   = "standing-charges"
+	chargeDayUnitRates      = "day-unit-rates"
+	chargeNightUnitRates    = "night-unit-rates"
 )
 
 // GSPs provides a list of Grid Supply Points (GSP)
@@ -58,7 +63,16 @@ type GridSupplyPoint struct {
 // Client represents a Client to be used with the API
 type Client struct {
 	httpClient *http.Client
-	URL        string
+	apiKey     string
+	baseURL    string
+	userAgent  string
+	logger     Logger
+	cache      Cache
+	cacheTTLs  map[string]time.Duration
+	// retry configures retrying of failed requests, DefaultRetryPolicy by default
+	retry RetryPolicy
+	// limiter limits the rate of requests, including retries, if not nil
+	limiter *RateLimiter
 }
 
 // MeterPoint represents a meter point
@@ -69,6 +83,14 @@ type MeterPoint struct {
 	ProfileClass int
 }
 
+// GasMeterPoint represents a gas meter point
+type GasMeterPoint struct {
+	MPRN string
+	// ConsumptionStandard is the estimated annual consumption in kWh
+	ConsumptionStandard int
+	Meters              []Meter
+}
+
 // Consumption represents a power consumption in a given interval
 type Consumption struct {
 	// Value represents meter reading for the interval
@@ -84,6 +106,9 @@ type Consumption struct {
 	IntervalEnd   time.Time `json:"interval_end"`
 }
 
+// ConsumptionPage represents a single page of meter consumption
+type ConsumptionPage = Page[Consumption]
+
 // ConsumptionOption represents optional parameters for API.GetMeterConsumption
 type ConsumptionOption struct {
 	From     time.Time
@@ -96,24 +121,45 @@ type ConsumptionOption struct {
 // Product represents an Octopus Energy product
 // https://developer.octopus.energy/docs/api/#retrieve-a-product
 type Product struct {
-	Code                      string                       `json:"code"`
-	Direction                 string                       `json:"direciton"`
-	FullName                  string                       `json:"full_name"`
-	DisplayName               string                       `json:"display_name"`
-	Description               string                       `json:"description"`
-	IsVariable                bool                         `json:"is_variable"`
-	IsGreen                   bool                         `json:"is_green"`
-	IsTracker                 bool                         `json:"is_tracker"`
-	IsPrepay                  bool                         `json:"is_prepay"`
-	IsBusiness                bool                         `json:"is_business"`
-	IsRestricted              bool                         `json:"is_restricted"`
-	Term                      int                          `json:"term"`
-	AvailableFrom             time.Time                    `json:"available_from"`
-	AvailableTo               time.Time                    `json:"available_to"`
-	Links                     []Link                       `json:"links"`
-	SingleRegisterElecTariffs map[string]map[string]Tariff `json:"single_register_electricity_tariffs"`
-	DualRegisterElecTariffs   map[string]map[string]Tariff `json:"dual_register_electricity_tariffs"`
-	SingleRegisterGasTariffs  map[string]map[string]Tariff `json:"single_register_gas_tariffs"`
+	Code                      string                                   `json:"code"`
+	Direction                 string                                   `json:"direciton"`
+	FullName                  string                                   `json:"full_name"`
+	DisplayName               string                                   `json:"display_name"`
+	Description               string                                   `json:"description"`
+	IsVariable                bool                                     `json:"is_variable"`
+	IsGreen                   bool                                     `json:"is_green"`
+	IsTracker                 bool                                     `json:"is_tracker"`
+	IsPrepay                  bool                                     `json:"is_prepay"`
+	IsBusiness                bool                                     `json:"is_business"`
+	IsRestricted              bool                                     `json:"is_restricted"`
+	Term                      int                                      `json:"term"`
+	AvailableFrom             time.Time                                `json:"available_from"`
+	AvailableTo               time.Time                                `json:"available_to"`
+	TariffsActiveAt           time.Time                                `json:"tariffs_active_at"`
+	Brand                     string                                   `json:"brand"`
+	Links                     []Link                                   `json:"links"`
+	SingleRegisterElecTariffs map[string]map[string]Tariff             `json:"single_register_electricity_tariffs"`
+	DualRegisterElecTariffs   map[string]map[string]DualRegisterTariff `json:"dual_register_electricity_tariffs"`
+	SingleRegisterGasTariffs  map[string]map[string]Tariff             `json:"single_register_gas_tariffs"`
+}
+
+// ListProductsOption represents optional parameters for API.ListProductsWithOptions.
+// Filters left nil are not applied.
+type ListProductsOption struct {
+	IsVariable *bool
+	IsGreen    *bool
+	IsTracker  *bool
+	IsPrepay   *bool
+	IsBusiness *bool
+	// AvailableAt lists products available at a given time, instead of now
+	AvailableAt time.Time
+	Brand       string
+}
+
+// ProductOption represents optional parameters for API.GetProductWithOptions
+type ProductOption struct {
+	// TariffsActiveAt retrieves tariffs active at a given time, instead of now
+	TariffsActiveAt time.Time
 }
 
 // Link represents a hyperlink
@@ -139,9 +185,96 @@ type Tariff struct {
 	StandardUnitRateIncVAT float32 `json:"standard_unit_rate_inc_vat"`
 }
 
-type productJSON struct {
-	Count    int       `json:"count"`
-	Next     string    `json:"next"`
-	Previous string    `json:"previous"`
-	Results  []Product `json:"results"`
+// DualRegisterTariff represents an Octopus Energy dual register (e.g. Economy 7) electricity tariff.
+// Standard unit rates of the embedded Tariff are not set for dual register tariffs.
+type DualRegisterTariff struct {
+	Tariff
+	DayUnitRateExcVAT   float32 `json:"day_unit_rate_exc_vat"`
+	DayUnitRateIncVAT   float32 `json:"day_unit_rate_inc_vat"`
+	NightUnitRateExcVAT float32 `json:"night_unit_rate_exc_vat"`
+	NightUnitRateIncVAT float32 `json:"night_unit_rate_inc_vat"`
+}
+
+// Account represents an Octopus Energy account
+// https://developer.octopus.energy/docs/api/#accounts
+type Account struct {
+	Number     string     `json:"number"`
+	Properties []Property `json:"properties"`
+}
+
+// Property represents a property supplied under an account
+type Property struct {
+	ID           int       `json:"id"`
+	MovedInAt    time.Time `json:"moved_in_at"`
+	MovedOutAt   time.Time `json:"moved_out_at"`
+	AddressLine1 string    `json:"address_line_1"`
+	AddressLine2 string    `json:"address_line_2"`
+	AddressLine3 string    `json:"address_line_3"`
+	Town         string    `json:"town"`
+	County       string    `json:"county"`
+	Postcode     string    `json:"postcode"`
+
+	ElecMeterPoints []AccountElecMeterPoint `json:"electricity_meter_points"`
+	GasMeterPoints  []AccountGasMeterPoint  `json:"gas_meter_points"`
+}
+
+// AccountElecMeterPoint represents an electricity meter point of a property
+type AccountElecMeterPoint struct {
+	MPAN                string      `json:"mpan"`
+	ProfileClass        int         `json:"profile_class"`
+	ConsumptionStandard int         `json:"consumption_standard"`
+	Meters              []Meter     `json:"meters"`
+	Agreements          []Agreement `json:"agreements"`
+	IsExport            bool        `json:"is_export"`
+}
+
+// AccountGasMeterPoint represents a gas meter point of a property
+type AccountGasMeterPoint struct {
+	MPRN                string      `json:"mprn"`
+	ConsumptionStandard int         `json:"consumption_standard"`
+	Meters              []Meter     `json:"meters"`
+	Agreements          []Agreement `json:"agreements"`
+}
+
+// Meter represents a physical meter installed at a meter point
+type Meter struct {
+	SerialNumber string     `json:"serial_number"`
+	Registers    []Register `json:"registers"`
+}
+
+// Register represents a register of an electricity meter
+type Register struct {
+	Identifier           string `json:"identifier"`
+	Rate                 string `json:"rate"`
+	IsSettlementRegister bool   `json:"is_settlement_register"`
+}
+
+// Agreement represents a tariff a meter point was supplied on
+type Agreement struct {
+	TariffCode string    `json:"tariff_code"`
+	ValidFrom  time.Time `json:"valid_from"`
+	// ValidTo is zero if the agreement has no end date
+	ValidTo time.Time `json:"valid_to"`
+}
+
+type gspJSON struct {
+	GroupID string `json:"group_id"`
+}
+
+// Rate represents a tariff charge valid over a period of time
+// https://developer.octopus.energy/docs/api/#list-tariff-charges
+type Rate struct {
+	ValueExcVAT float32   `json:"value_exc_vat"`
+	ValueIncVAT float32   `json:"value_inc_vat"`
+	ValidFrom   time.Time `json:"valid_from"`
+	// ValidTo is zero if the rate has no end date
+	ValidTo       time.Time `json:"valid_to"`
+	PaymentMethod string    `json:"payment_method"`
+}
+
+// RateOption represents optional parameters for retrieving tariff charges
+type RateOption struct {
+	From     time.Time
+	To       time.Time
+	PageSize int
 }